@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/apidiff"
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// cmdDiff lists the changes to the exported API of a pkg between two
+// versions. Each version arg is either pkg@version (a module version,
+// fetched into the module cache), or a git ref of the current module,
+// in which case the pkg is that of the current dir. If only one version
+// arg is supplied, it is compared against the working tree: for
+// pkg@version, that's the local source of pkg (see resolve.Local). If
+// -html is set, a side-by-side view is also opened in the browser.
+func cmdDiff(app *App) error {
+	if len(app.args) < 1 || len(app.args) > 2 {
+		return fmt.Errorf("diff command takes one or two args, e.g. pkg@v1.2.0 pkg@v1.3.0, or a git ref")
+	}
+
+	oldSrc, err := loadAPISource(app, app.args[0])
+	if err != nil {
+		return err
+	}
+	defer oldSrc.cleanup()
+
+	var newSrc *apiSource
+	if len(app.args) == 2 {
+		newSrc, err = loadAPISource(app, app.args[1])
+	} else {
+		newSrc, err = workingTreeAPISource(app, oldSrc)
+	}
+	if err != nil {
+		return err
+	}
+	defer newSrc.cleanup()
+
+	oldAPI, err := apidiff.Load(app.ctx, buildContext(app), oldSrc.dir)
+	if err != nil {
+		return fmt.Errorf("%s: %v", oldSrc.name, err)
+	}
+	newAPI, err := apidiff.Load(app.ctx, buildContext(app), newSrc.dir)
+	if err != nil {
+		return fmt.Errorf("%s: %v", newSrc.name, err)
+	}
+
+	changes := apidiff.Diff(oldAPI, newAPI)
+	apidiff.PrintChanges(os.Stdout, oldSrc.name, newSrc.name, changes)
+
+	if !app.flagHTML {
+		return nil
+	}
+
+	page, err := apidiff.ChangesHTML(oldSrc.name, newSrc.name, changes)
+	if err != nil {
+		return err
+	}
+	return serveAndOpen(app, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	}))
+}
+
+// apiSource is a dir containing a version of a pkg's source.
+type apiSource struct {
+	// name describes the source version, e.g. "github.com/my/pkg@v1.2.0".
+	name string
+	// pkg is the pkg's import path, if the source is a module version.
+	pkg string
+	dir string
+	// cleanup releases any resources (e.g. a git worktree) held by the
+	// source. It is never nil.
+	cleanup func()
+}
+
+// loadAPISource returns the source of the version of the pkg described
+// by arg, which is pkg@version or a git ref of the current module.
+func loadAPISource(app *App, arg string) (*apiSource, error) {
+	if i := strings.LastIndex(arg, "@"); i > 0 && !strings.HasPrefix(arg[i+1:], "{") {
+		// Note that git refs can contain "@{", e.g. "main@{1}"
+		return moduleAPISource(app, arg[:i], arg[i+1:])
+	}
+	return gitRefAPISource(app, app.cwd, arg)
+}
+
+// workingTreeAPISource returns the working tree source of the pkg
+// of old: the local source of the pkg if old is a module version, or
+// otherwise the current dir.
+func workingTreeAPISource(app *App, old *apiSource) (*apiSource, error) {
+	if old.pkg == "" {
+		return &apiSource{name: "working tree", dir: app.cwd, cleanup: func() {}}, nil
+	}
+
+	arg := resolve.ParseArg(app.cwd, []string{old.pkg})
+	bp, err := resolve.Local(app.ctx, buildContext(app), app.cwd, arg)
+	if err != nil {
+		return nil, fmt.Errorf("%v: to compare %s with another version, supply it as the second arg", err, old.name)
+	}
+	return &apiSource{name: "working tree", pkg: old.pkg, dir: bp.Dir, cleanup: func() {}}, nil
+}
+
+// moduleAPISource downloads the module version containing pkg into the
+// module cache, and returns the source of pkg.
+func moduleAPISource(app *App, pkg, version string) (*apiSource, error) {
+	mod, err := resolve.DownloadModule(app.ctx, pkg, version)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(mod.Dir, filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(pkg, mod.Path), "/")))
+	return &apiSource{name: pkg + "@" + version, pkg: pkg, dir: dir, cleanup: func() {}}, nil
+}
+
+// gitRefAPISource checks out git ref of the repo containing dir into
+// a temporary worktree, and returns the source of the pkg in dir at ref.
+func gitRefAPISource(app *App, dir, ref string) (*apiSource, error) {
+	wtDir, cleanup, err := apidiff.Worktree(app.ctx, dir, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiSource{name: ref, dir: wtDir, cleanup: cleanup}, nil
+}
@@ -0,0 +1,377 @@
+// Package apidiff compares versions of the exported API of a pkg: their
+// declarations, and their doc comments.
+package apidiff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// Sym is an exported symbol of a pkg.
+type Sym struct {
+	// ID is the symbol's godoc fragment, e.g. "Println" or "Buffer.Len".
+	// The pkg itself is the symbol "pkg-overview".
+	ID string
+	// Sig is the symbol's declaration, normalized so that insignificant
+	// differences (e.g. param names) don't show up as changes.
+	Sig string
+	// Doc is the symbol's doc comment text.
+	Doc string
+	// Interface is true if the symbol is a method of an interface type.
+	Interface bool
+}
+
+// Load returns the exported symbols of the pkg in dir, keyed by ID.
+// Only the pkg files that match bctx are considered.
+func Load(ctx context.Context, bctx *build.Context, dir string) (map[string]Sym, error) {
+	bp, err := bctx.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	api := map[string]Sym{}
+	for _, f := range resolve.GoFiles(bp) {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, f), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		// The pkg itself is a symbol, so that changes to the pkg doc
+		// can be detected.
+		sym := api["pkg-overview"]
+		sym.ID = "pkg-overview"
+		sym.Sig = "package " + file.Name.Name
+		sym.Doc += file.Doc.Text()
+		api[sym.ID] = sym
+
+		for _, decl := range file.Decls {
+			for _, sym := range declSyms(fset, decl) {
+				api[sym.ID] = sym
+			}
+		}
+	}
+	return api, nil
+}
+
+// IsNoPkg returns true if err, returned by Load for dir, is because
+// there's no pkg in dir: i.e. dir doesn't exist, or has no buildable Go
+// files.
+func IsNoPkg(dir string, err error) bool {
+	if _, statErr := os.Stat(dir); os.IsNotExist(statErr) {
+		return true
+	}
+	var noGoErr *build.NoGoError
+	return errors.As(err, &noGoErr)
+}
+
+// declSyms returns the exported symbols declared by decl.
+func declSyms(fset *token.FileSet, decl ast.Decl) []Sym {
+	var syms []Sym
+
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if !decl.Name.IsExported() {
+			break
+		}
+
+		sig := "func "
+		id := decl.Name.Name
+		if decl.Recv != nil {
+			recv := resolve.RecvTypeName(decl.Recv.List[0].Type)
+			if !ast.IsExported(recv) {
+				break
+			}
+			id = recv + "." + id
+
+			recvType := recv
+			if _, ok := decl.Recv.List[0].Type.(*ast.StarExpr); ok {
+				recvType = "*" + recv
+			}
+			sig += "(" + recvType + ") "
+		}
+		sig += decl.Name.Name + strings.TrimPrefix(sprintNode(fset, stripParamNames(decl.Type)), "func")
+		syms = append(syms, Sym{ID: id, Sig: sig, Doc: decl.Doc.Text()})
+
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.IsExported() {
+					syms = append(syms, typeSyms(fset, decl, spec)...)
+				}
+
+			case *ast.ValueSpec:
+				doc := spec.Doc
+				if doc == nil {
+					doc = decl.Doc
+				}
+
+				for _, ident := range spec.Names {
+					if !ident.IsExported() {
+						continue
+					}
+
+					sig := decl.Tok.String() + " " + ident.Name
+					if spec.Type != nil {
+						sig += " " + sprintNode(fset, spec.Type)
+					}
+					syms = append(syms, Sym{ID: ident.Name, Sig: sig, Doc: doc.Text()})
+				}
+			}
+		}
+	}
+
+	return syms
+}
+
+// typeSyms returns the symbols for type spec: the type itself, and
+// its exported fields or interface methods.
+func typeSyms(fset *token.FileSet, decl *ast.GenDecl, spec *ast.TypeSpec) []Sym {
+	doc := spec.Doc
+	if doc == nil {
+		doc = decl.Doc
+	}
+
+	name := spec.Name.Name
+	sig := "type " + name + " "
+	if spec.Assign.IsValid() {
+		sig += "= "
+	}
+
+	var members []Sym
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		sig += "struct"
+		for _, field := range t.Fields.List {
+			names := field.Names
+			if len(names) == 0 {
+				// embedded field
+				names = []*ast.Ident{ast.NewIdent(resolve.RecvTypeName(field.Type))}
+			}
+			for _, ident := range names {
+				if ident.IsExported() {
+					members = append(members, Sym{
+						ID:  name + "." + ident.Name,
+						Sig: "field " + name + "." + ident.Name + " " + sprintNode(fset, field.Type),
+						Doc: field.Doc.Text(),
+					})
+				}
+			}
+		}
+
+	case *ast.InterfaceType:
+		sig += "interface"
+		for _, field := range t.Methods.List {
+			if len(field.Names) == 0 {
+				// embedded interface
+				members = append(members, Sym{
+					ID:        name + "." + sprintNode(fset, field.Type),
+					Sig:       "embedded " + name + "." + sprintNode(fset, field.Type),
+					Interface: true,
+				})
+				continue
+			}
+
+			for _, ident := range field.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				ft, _ := field.Type.(*ast.FuncType)
+				members = append(members, Sym{
+					ID:        name + "." + ident.Name,
+					Sig:       "method " + name + "." + ident.Name + strings.TrimPrefix(sprintNode(fset, stripParamNames(ft)), "func"),
+					Doc:       field.Doc.Text(),
+					Interface: true,
+				})
+			}
+		}
+
+	default:
+		sig += sprintNode(fset, spec.Type)
+	}
+
+	return append([]Sym{{ID: name, Sig: sig, Doc: doc.Text()}}, members...)
+}
+
+// stripParamNames returns a copy of ft without param and result names,
+// so that renaming a param is not treated as an API change.
+func stripParamNames(ft *ast.FuncType) *ast.FuncType {
+	if ft == nil {
+		return &ast.FuncType{}
+	}
+
+	strip := func(fl *ast.FieldList) *ast.FieldList {
+		if fl == nil {
+			return nil
+		}
+		res := &ast.FieldList{}
+		for _, field := range fl.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				res.List = append(res.List, &ast.Field{Type: field.Type})
+			}
+		}
+		return res
+	}
+
+	return &ast.FuncType{TypeParams: ft.TypeParams, Params: strip(ft.Params), Results: strip(ft.Results)}
+}
+
+// sprintNode returns node's source on a single line.
+func sprintNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	_ = (&printer.Config{Mode: printer.RawFormat}).Fprint(&buf, fset, node)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// Change is a difference between two versions of an API symbol.
+type Change struct {
+	ID string
+	// Old and New are the symbol's signature in each version. For an
+	// added symbol, Old is empty; for a removed symbol, New is empty.
+	Old, New string
+	// Incompatible is true if the change may break existing code.
+	Incompatible bool
+}
+
+// Diff returns the changes between oldAPI and newAPI, incompatible
+// changes first, and otherwise sorted by ID.
+func Diff(oldAPI, newAPI map[string]Sym) []Change {
+	var changes []Change
+
+	for id, o := range oldAPI {
+		n, ok := newAPI[id]
+		switch {
+		case !ok:
+			changes = append(changes, Change{ID: id, Old: o.Sig, Incompatible: true})
+		case n.Sig != o.Sig:
+			changes = append(changes, Change{ID: id, Old: o.Sig, New: n.Sig, Incompatible: true})
+		}
+	}
+
+	for id, n := range newAPI {
+		if _, ok := oldAPI[id]; !ok {
+			// Adding a method to an interface breaks its implementations.
+			changes = append(changes, Change{ID: id, New: n.Sig, Incompatible: n.Interface})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Incompatible != changes[j].Incompatible {
+			return changes[i].Incompatible
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+// PrintChanges writes changes to w, in the style of the apidiff tool.
+func PrintChanges(w io.Writer, oldName, newName string, changes []Change) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "No exported API changes between %s and %s\n", oldName, newName)
+		return
+	}
+
+	fmt.Fprintf(w, "Exported API changes between %s and %s:\n", oldName, newName)
+	incompatibleHeader, compatibleHeader := false, false
+	for _, c := range changes {
+		if c.Incompatible && !incompatibleHeader {
+			fmt.Fprintln(w, "\nIncompatible changes:")
+			incompatibleHeader = true
+		}
+		if !c.Incompatible && !compatibleHeader {
+			fmt.Fprintln(w, "\nCompatible changes:")
+			compatibleHeader = true
+		}
+
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(w, "+ %s\n", c.New)
+		case c.New == "":
+			fmt.Fprintf(w, "- %s\n", c.Old)
+		default:
+			fmt.Fprintf(w, "~ %s\n  %s\n", c.Old, c.New)
+		}
+	}
+}
+
+var apiDiffTpl = template.Must(template.New("apidiff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API diff: {{.Old}} .. {{.New}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+td { font-family: monospace; white-space: pre-wrap; }
+.removed { background: #ffeef0; }
+.added { background: #e6ffed; }
+.changed { background: #fff5b1; }
+.incompatible td:first-child { font-weight: bold; color: #b31d28; }
+</style>
+</head>
+<body>
+<h1>API diff</h1>
+{{if .Changes}}
+<table>
+<tr><th>Symbol</th><th>{{.Old}}</th><th>{{.New}}</th></tr>
+{{range .Changes}}<tr class="{{.Class}}{{if .Incompatible}} incompatible{{end}}"><td>{{.ID}}{{if .Incompatible}} (incompatible){{end}}</td><td>{{.Old}}</td><td>{{.New}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No exported API changes between {{.Old}} and {{.New}}.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// ChangesHTML returns a HTML page with a side-by-side view of changes.
+func ChangesHTML(oldName, newName string, changes []Change) ([]byte, error) {
+	type row struct {
+		ID, Old, New, Class string
+		Incompatible        bool
+	}
+
+	data := struct {
+		Old, New string
+		Changes  []row
+	}{Old: oldName, New: newName}
+
+	for _, c := range changes {
+		r := row{ID: c.ID, Old: c.Old, New: c.New, Class: "changed", Incompatible: c.Incompatible}
+		switch {
+		case c.Old == "":
+			r.Class = "added"
+		case c.New == "":
+			r.Class = "removed"
+		}
+		data.Changes = append(data.Changes, r)
+	}
+
+	var buf bytes.Buffer
+	err := apiDiffTpl.Execute(&buf, data)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,60 @@
+package apidiff
+
+import (
+	"context"
+	"go/build"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	oldAPI, err := Load(context.Background(), &build.Default, "../testdata/apidiff/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAPI, err := Load(context.Background(), &build.Default, "../testdata/apidiff/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{
+		{ID: "Changed", Old: "func Changed(int) error", New: "func Changed(int, string) error", Incompatible: true},
+		{ID: "I.Close", New: "method I.Close() error", Incompatible: true},
+		{ID: "Removed", Old: "func Removed()", Incompatible: true},
+		{ID: "T.M", Old: "func (T) M()", New: "func (*T) M()", Incompatible: true},
+		{ID: "Added", New: "func Added()"},
+		{ID: "T.B", New: "field T.B string"},
+	}
+
+	got := Diff(oldAPI, newAPI)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+
+	if oldAPI["pkg-overview"].Doc != "Package api is v1 of a pkg used to test gohdoc -diff.\n" {
+		t.Errorf("unexpected pkg doc: %q", oldAPI["pkg-overview"].Doc)
+	}
+}
+
+func TestChangesHTML(t *testing.T) {
+	changes := []Change{
+		{ID: "Removed", Old: "func Removed()", Incompatible: true},
+		{ID: "Added", New: "func Added(<T>)"},
+	}
+
+	b, err := ChangesHTML("v1", "v2", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+
+	for _, want := range []string{
+		`<tr class="removed incompatible"><td>Removed (incompatible)</td><td>func Removed()</td><td></td></tr>`,
+		`<tr class="added"><td>Added</td><td></td><td>func Added(&lt;T&gt;)</td></tr>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page should contain %s", want)
+		}
+	}
+}
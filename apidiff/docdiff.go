@@ -0,0 +1,186 @@
+package apidiff
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DocChange is a change to the doc text of a symbol.
+type DocChange struct {
+	ID string
+	// Sig is the symbol's signature (in the working tree, if the
+	// symbol exists there).
+	Sig string
+	// Kind is one of "added", "removed" or "reworded".
+	Kind string
+	// Diff is the doc text, with changes marked up using <ins> and <del>.
+	Diff template.HTML
+}
+
+// DiffDocs returns the doc changes for each symbol between oldAPI and
+// newAPI, with the pkg doc first, and otherwise sorted by ID.
+func DiffDocs(oldAPI, newAPI map[string]Sym) []DocChange {
+	var changes []DocChange
+
+	for id, n := range newAPI {
+		o, ok := oldAPI[id]
+		switch {
+		case !ok && n.Doc != "":
+			changes = append(changes, DocChange{ID: id, Sig: n.Sig, Kind: "added", Diff: wordDiffHTML("", n.Doc)})
+		case ok && o.Doc != n.Doc:
+			changes = append(changes, DocChange{ID: id, Sig: n.Sig, Kind: "reworded", Diff: wordDiffHTML(o.Doc, n.Doc)})
+		}
+	}
+
+	for id, o := range oldAPI {
+		if _, ok := newAPI[id]; !ok && o.Doc != "" {
+			changes = append(changes, DocChange{ID: id, Sig: o.Sig, Kind: "removed", Diff: wordDiffHTML(o.Doc, "")})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ID == "pkg-overview" || changes[j].ID == "pkg-overview" {
+			return changes[i].ID == "pkg-overview"
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+var wordRegex = regexp.MustCompile(`\s+|\S+`)
+
+// docTokens splits text into words and whitespace. Whitespace is
+// normalized to a paragraph break or a single space, so that rewrapping
+// a comment doesn't show up as a change.
+func docTokens(text string) []string {
+	toks := wordRegex.FindAllString(text, -1)
+	for i, tok := range toks {
+		if strings.TrimSpace(tok) != "" {
+			continue
+		}
+		if strings.Count(tok, "\n") > 1 {
+			toks[i] = "\n\n"
+		} else {
+			toks[i] = " "
+		}
+	}
+	return toks
+}
+
+// wordDiffHTML returns the HTML-escaped new text, with the words removed
+// from old marked with <del>, and the words added marked with <ins>.
+func wordDiffHTML(oldText, newText string) template.HTML {
+	a, b := docTokens(strings.TrimSpace(oldText)), docTokens(strings.TrimSpace(newText))
+
+	// Trim the common prefix and suffix, to reduce the size of the LCS table.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	// lcs[i][j] is the length of the longest common subsequence of ma[i:] and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	var del, ins []string
+	flush := func() {
+		if len(del) > 0 {
+			buf.WriteString("<del>" + template.HTMLEscapeString(strings.Join(del, "")) + "</del>")
+		}
+		if len(ins) > 0 {
+			buf.WriteString("<ins>" + template.HTMLEscapeString(strings.Join(ins, "")) + "</ins>")
+		}
+		del, ins = nil, nil
+	}
+	same := func(tok string) {
+		flush()
+		buf.WriteString(template.HTMLEscapeString(tok))
+	}
+
+	for _, tok := range b[:pre] {
+		same(tok)
+	}
+
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			same(ma[i])
+			i++
+			j++
+		case j < len(mb) && (i == len(ma) || lcs[i][j+1] >= lcs[i+1][j]):
+			ins = append(ins, mb[j])
+			j++
+		default:
+			del = append(del, ma[i])
+			i++
+		}
+	}
+	flush()
+
+	for _, tok := range b[len(b)-suf:] {
+		same(tok)
+	}
+
+	return template.HTML(buf.String())
+}
+
+var docDiffTpl = template.Must(template.New("docdiff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Doc changes: {{.Ref}} .. working tree</title>
+<style>
+body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+pre.sig { background: #f6f8fa; padding: 0.5em; }
+.doc { white-space: pre-wrap; line-height: 1.4; }
+del { background: #ffeef0; color: #b31d28; }
+ins { background: #e6ffed; color: #22863a; text-decoration: none; }
+.kind { font-size: small; color: #666; }
+</style>
+</head>
+<body>
+<h1>Doc changes: {{.Ref}} .. working tree</h1>
+{{range .Changes}}
+<h2 id="{{.ID}}">{{.ID}} <span class="kind">({{.Kind}})</span></h2>
+<pre class="sig">{{.Sig}}</pre>
+<div class="doc">{{.Diff}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+// DocChangesHTML returns a HTML page showing changes between git ref
+// and the working tree.
+func DocChangesHTML(ref string, changes []DocChange) ([]byte, error) {
+	data := struct {
+		Ref     string
+		Changes []DocChange
+	}{Ref: ref, Changes: changes}
+
+	var buf bytes.Buffer
+	err := docDiffTpl.Execute(&buf, data)
+	return buf.Bytes(), err
+}
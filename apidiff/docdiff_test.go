@@ -0,0 +1,67 @@
+package apidiff
+
+import (
+	"context"
+	"go/build"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestWordDiffHTML(t *testing.T) {
+	testCases := []struct {
+		old, new string
+		want     template.HTML
+	}{
+		{old: "", new: "", want: ""},
+		{old: "same text", new: "same text", want: "same text"},
+		{old: "same\ntext", new: "same text", want: "same text"},
+		{old: "", new: "all new", want: "<ins>all new</ins>"},
+		{old: "all gone", new: "", want: "<del>all gone</del>"},
+		{old: "Foo returns a value.", new: "Foo returns the value.", want: "Foo returns <del>a</del><ins>the</ins> value."},
+		{old: "Foo returns.", new: "Foo quickly returns.", want: "Foo <ins>quickly </ins>returns."},
+		{old: "A <b> tag.", new: "A <i> tag.", want: "A <del>&lt;b&gt;</del><ins>&lt;i&gt;</ins> tag."},
+		{old: "Para one.\n\nPara two.", new: "Para one.\nPara two.", want: "Para one.<del>\n\n</del><ins> </ins>Para two."},
+	}
+
+	for _, tc := range testCases {
+		got := wordDiffHTML(tc.old, tc.new)
+		if got != tc.want {
+			t.Errorf("wordDiffHTML(%q, %q):\nwant: %q\n got: %q", tc.old, tc.new, tc.want, got)
+		}
+	}
+}
+
+func TestDiffDocs(t *testing.T) {
+	oldAPI, err := Load(context.Background(), &build.Default, "../testdata/apidiff/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAPI, err := Load(context.Background(), &build.Default, "../testdata/apidiff/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := DiffDocs(oldAPI, newAPI)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Kind+" "+c.ID)
+	}
+	want := "reworded pkg-overview, added Added, removed Removed"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("want %s but got %s", want, strings.Join(got, ", "))
+	}
+
+	if want := template.HTML("Package api is <del>v1</del><ins>v2</ins> of a pkg used to test gohdoc -diff."); changes[0].Diff != want {
+		t.Errorf("want %q but got %q", want, changes[0].Diff)
+	}
+
+	page, err := DocChangesHTML("HEAD", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `<div class="doc"><ins>Added is added in v2.`) {
+		t.Errorf("page should contain added doc for Added:\n%s", page)
+	}
+}
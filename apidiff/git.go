@@ -0,0 +1,68 @@
+package apidiff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree checks out git ref of the repo containing dir into a temporary
+// worktree, so that the version of a pkg at ref can be loaded. It returns
+// the path of dir within the worktree, and a func that removes the worktree.
+func Worktree(ctx context.Context, dir, ref string) (wtDir string, cleanup func(), err error) {
+	git := func(args ...string) (string, error) {
+		return gitCmd(ctx, dir, args...)
+	}
+
+	prefix, err := git("rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "gohdoc_worktree_")
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, err = git("worktree", "add", "--detach", tmpDir, ref)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return "", nil, err
+	}
+
+	cleanup = func() {
+		// Not ctx: the worktree must be removed even if gohdoc was
+		// interrupted, e.g. to stop serving a -preview-diff page.
+		if _, err := gitCmd(context.Background(), dir, "worktree", "remove", "--force", tmpDir); err != nil {
+			log.Printf("failed to remove git worktree: %v", err)
+		}
+		_ = os.RemoveAll(tmpDir)
+	}
+
+	log.Printf("checked out %s to git worktree %s", ref, tmpDir)
+	return filepath.Join(tmpDir, filepath.FromSlash(prefix)), cleanup, nil
+}
+
+// IsGitRef returns true if arg names a git commit (e.g. a branch, tag
+// or commit hash) of the repo containing dir.
+func IsGitRef(ctx context.Context, dir, arg string) bool {
+	_, err := gitCmd(ctx, dir, "rev-parse", "--verify", "--quiet", arg+"^{commit}")
+	return err == nil
+}
+
+// gitCmd runs git with args in dir, returning its trimmed output.
+func gitCmd(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, bytes.TrimSpace(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
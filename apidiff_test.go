@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"go/build"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/gohdoc/server"
+)
+
+func TestWorkingTreeAPISource(t *testing.T) {
+	app := &App{srv: &server.Server{}, cwd: ".", ctx: context.Background()}
+
+	testCases := []struct {
+		old     apiSource
+		wantDir string
+		wantErr bool
+	}{
+		// A git ref is compared against the current dir
+		{old: apiSource{name: "v1.2.0"}, wantDir: "."},
+		// A module version is compared against its pkg's local source,
+		// not against the current dir
+		{old: apiSource{name: "fmt@v1.0.0", pkg: "fmt"}, wantDir: filepath.Join(build.Default.GOROOT, "src", "fmt")},
+		{old: apiSource{name: "example.com/nope@v1.0.0", pkg: "example.com/nope"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		src, err := workingTreeAPISource(app, &tc.old)
+		if tc.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "second arg") {
+				t.Errorf("%s: want error suggesting a second arg but got %v", tc.old.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.old.name, err)
+			continue
+		}
+		if src.dir != tc.wantDir {
+			t.Errorf("%s: want dir %s but got %s", tc.old.name, tc.wantDir, src.dir)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package browser opens URLs in the system's web browser.
+package browser
+
+import (
+	"context"
+)
+
+// Opener opens URLs in a web browser.
+type Opener interface {
+	Open(ctx context.Context, url string) error
+}
+
+// OpenerFunc is an adapter to allow the use of ordinary funcs as
+// an Opener. For example, OpenerFunc(Open) is the system's browser.
+type OpenerFunc func(ctx context.Context, url string) error
+
+// Open calls f(ctx, url).
+func (f OpenerFunc) Open(ctx context.Context, url string) error {
+	return f(ctx, url)
+}
+
+// Open opens a browser for url. It delegates creation of the platform-specific
+// exec.Cmd to build tag-gated implementations of openCmd.
+func Open(ctx context.Context, url string) error {
+	return openCmd(ctx, url).Run()
+}
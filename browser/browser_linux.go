@@ -1,10 +1,10 @@
-package main
+package browser
 
 import (
 	"context"
 	"os/exec"
 )
 
-func openBrowserCmd(ctx context.Context, url string) *exec.Cmd {
+func openCmd(ctx context.Context, url string) *exec.Cmd {
 	return exec.CommandContext(ctx, "xdg-open", url) // linux
 }
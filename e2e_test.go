@@ -0,0 +1,661 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/scrape"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+// fakeDocServer is a fake godoc http server. It serves a testdata
+// pkg list page at /pkg/, and a minimal page for each listed pkg,
+// and for each command (under /cmd/).
+type fakeDocServer struct {
+	pkgPage []byte
+	pkgs    map[string]bool
+	cmds    map[string]bool
+	// pkgList is the list of pkgs and commands, for use as the
+	// local pkg list.
+	pkgList []resolve.Package
+}
+
+func newFakeDocServer(t *testing.T) *fakeDocServer {
+	b, err := ioutil.ReadFile("testdata/pkg_1.10.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := scrape.Packages(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := &fakeDocServer{pkgPage: b, pkgs: map[string]bool{}, cmds: map[string]bool{}}
+	for _, pkg := range pkgs {
+		ds.pkgs[pkg] = true
+		ds.pkgList = append(ds.pkgList, resolve.Package{ImportPath: pkg})
+	}
+	for _, cmd := range []string{"cmd/vet", "github.com/my/tool"} {
+		ds.cmds[cmd] = true
+		ds.pkgList = append(ds.pkgList, resolve.Package{ImportPath: cmd, Command: true})
+	}
+	return ds
+}
+
+func (ds *fakeDocServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/pkg" || r.URL.Path == "/pkg/" {
+		_, _ = w.Write(ds.pkgPage)
+		return
+	}
+
+	var pkg string
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/pkg/"):
+		pkg = strings.Trim(strings.TrimPrefix(r.URL.Path, "/pkg/"), "/")
+		if !ds.pkgs[pkg] {
+			pkg = ""
+		}
+	case strings.HasPrefix(r.URL.Path, "/cmd/"):
+		pkg = strings.Trim(strings.TrimPrefix(r.URL.Path, "/cmd/"), "/")
+		if !ds.cmds[pkg] && !ds.cmds["cmd/"+pkg] {
+			pkg = ""
+		}
+	}
+	if pkg == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprintf(w, `<html><body><h1>Package %s</h1>
+<h2 id="Marshal">func Marshal</h2>
+<h2 id="Buffer.Len">func (*Buffer) Len</h2>
+</body></html>`, pkg)
+}
+
+// fakeClient is a server.HTTPClient that sends the requests for each
+// port that is up to the fake godoc http server at host. The requests
+// for other ports fail, as if there were no server listening.
+type fakeClient struct {
+	mu   sync.Mutex
+	host string
+	up   map[string]bool
+}
+
+func (c *fakeClient) setUp(port string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.up[port] = true
+}
+
+func (c *fakeClient) setDown(port string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.up, port)
+}
+
+func (c *fakeClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	up := c.up[req.URL.Port()]
+	c.mu.Unlock()
+	if !up {
+		return nil, errors.New("connection refused")
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Host = c.host
+	return http.DefaultClient.Do(req)
+}
+
+// fakeStarter is a server.Starter that brings client up, instead of
+// starting a process.
+type fakeStarter struct {
+	client *fakeClient
+	args   []string
+	env    []string
+}
+
+func (s *fakeStarter) Start(ctx context.Context, dir string, env []string, name string, args []string, out io.Writer) (server.Proc, error) {
+	if name != "godoc" {
+		return nil, fmt.Errorf("unexpected process %s", name)
+	}
+	s.args = args
+	s.env = env
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(args[0], "-http="))
+	if err != nil {
+		return nil, err
+	}
+	s.client.setUp(port)
+	return fakeProc{}, nil
+}
+
+type fakeProc struct{}
+
+func (fakeProc) Pid() int    { return 42 }
+func (fakeProc) Kill() error { return nil }
+
+// fakeClock is a server.Clock for which Sleep returns immediately.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// fakeBrowser is a browser.Opener that records the opened URLs.
+type fakeBrowser struct {
+	urls []string
+}
+
+func (b *fakeBrowser) Open(ctx context.Context, url string) error {
+	b.urls = append(b.urls, url)
+	return nil
+}
+
+// newTestApp returns an App whose godoc http server is ds, served by ts.
+// If serverUp is false, the server doesn't exist until the app starts it.
+func newTestApp(t *testing.T, ds *fakeDocServer, serverUp bool) (app *App, ts *httptest.Server) {
+	ts = httptest.NewServer(ds)
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeClient{host: u.Host, up: map[string]bool{}}
+	if serverUp {
+		client.setUp(u.Port())
+	}
+	app = &App{
+		srv: &server.Server{
+			Port:    port,
+			Client:  client,
+			Starter: &fakeStarter{client: client},
+			Clock:   &fakeClock{now: time.Now()},
+		},
+		browser: &fakeBrowser{},
+		listPkgs: func(ctx context.Context, dir string) ([]resolve.Package, error) {
+			return ds.pkgList, nil
+		},
+		cwd: "/go/src/github.com/neilotoole/gohdoc",
+		ctx: context.Background(),
+	}
+	return app, ts
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	outCh := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		outCh <- string(b)
+	}()
+
+	fn()
+	_ = w.Close()
+	return <-outCh
+}
+
+func TestE2EOpen(t *testing.T) {
+	ds := newFakeDocServer(t)
+
+	testCases := []struct {
+		args    []string
+		all     bool
+		goos    string
+		want    string
+		wantErr bool
+	}{
+		{want: "/pkg/github.com/neilotoole/gohdoc/"},
+		{args: []string{"#Marshal"}, want: "/pkg/github.com/neilotoole/gohdoc/#Marshal"},
+		{args: []string{"encoding/json"}, want: "/pkg/encoding/json/"},
+		{args: []string{"json#Marshal"}, want: "/pkg/encoding/json/#Marshal"},
+		{args: []string{"bytes#Buffer.Len"}, want: "/pkg/bytes/#Buffer.Len"},
+		{args: []string{"bytes#Buffer.Len"}, all: true, want: "/pkg/bytes/?m=all#Buffer.Len"},
+		{args: []string{"bytes#newBuffer"}, all: true, wantErr: true},
+		{args: []string{"nothing/matches/this"}, wantErr: true},
+		{args: []string{"json.marshal"}, want: "/pkg/encoding/json/#Marshal"},
+		{args: []string{"encoding/json.Marshal"}, want: "/pkg/encoding/json/#Marshal"},
+		{args: []string{"bytes.buffer.len"}, want: "/pkg/bytes/#Buffer.Len"},
+		{args: []string{"bytes.Len"}, want: "/pkg/bytes/#Buffer.Len"},
+		{args: []string{"bytes", "Buffer.Len"}, want: "/pkg/bytes/#Buffer.Len"},
+		{args: []string{"marshal"}, want: "/pkg/github.com/neilotoole/gohdoc/#Marshal"},
+		{args: []string{"json.MARSHAL"}, wantErr: true},
+		{args: []string{"bytes", "nope"}, wantErr: true},
+		{args: []string{"cmd/vet"}, want: "/cmd/vet/"},
+		{args: []string{"bytes#Buffer.Len"}, goos: "windows", want: "/pkg/bytes/?GOOS=windows#Buffer.Len"},
+		{args: []string{"bytes.buffer.len"}, all: true, goos: "windows", want: "/pkg/bytes/?m=all&GOOS=windows#Buffer.Len"},
+		{args: []string{"tool"}, want: "/cmd/github.com/my/tool/"},
+	}
+
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d__%s", i, strings.Join(tc.args, " ")), func(t *testing.T) {
+			app, ts := newTestApp(t, ds, true)
+			defer ts.Close()
+			app.args = tc.args
+			app.srv.Mode.All = tc.all
+			app.srv.Platform.GOOS = tc.goos
+
+			var err error
+			captureStdout(t, func() { err = cmdOpen(app) })
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := app.srv.BaseURL() + tc.want
+			urls := app.browser.(*fakeBrowser).urls
+			if len(urls) != 1 || urls[0] != want {
+				t.Errorf("want %q opened but got %v", want, urls)
+			}
+			if app.srv.Proc != nil {
+				t.Error("should not have started a server")
+			}
+		})
+	}
+}
+
+func TestE2EOpenStartsServer(t *testing.T) {
+	app, ts := newTestApp(t, newFakeDocServer(t), false)
+	defer ts.Close()
+	app.args = []string{"fmt"}
+
+	var err error
+	out := captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.srv.Proc == nil {
+		t.Fatal("should have started a server")
+	}
+	starter := app.srv.Starter.(*fakeStarter)
+	wantArg := fmt.Sprintf("-http=127.0.0.1:%d", app.srv.Port)
+	if len(starter.args) == 0 || starter.args[0] != wantArg {
+		t.Errorf("want server started with %s but got %v", wantArg, starter.args)
+	}
+
+	want := app.srv.BaseURL() + "/pkg/fmt/"
+	if strings.TrimSpace(out) != want {
+		t.Errorf("want %q printed but got %q", want, out)
+	}
+}
+
+func TestE2EOpenStartsServerVendorMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, text := range map[string]string{
+		"go.mod":             "module example.com/vendored\n\ngo 1.16\n\nrequire example.com/dep v1.0.0\n",
+		"vendor/modules.txt": "# example.com/dep v1.0.0\n## explicit\nexample.com/dep\n",
+	} {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The module is in vendor mode by default (go 1.14 or later)
+	goflags, ok := os.LookupEnv("GOFLAGS")
+	os.Unsetenv("GOFLAGS")
+	defer func() {
+		if ok {
+			os.Setenv("GOFLAGS", goflags)
+		}
+	}()
+
+	app, ts := newTestApp(t, newFakeDocServer(t), false)
+	defer ts.Close()
+	app.srv.Dir = dir
+	app.srv.Env = serverEnv
+	app.args = []string{"fmt"}
+
+	_ = captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starter := app.srv.Starter.(*fakeStarter)
+	const want = "GOFLAGS=-mod=vendor"
+	if len(starter.env) == 0 || starter.env[len(starter.env)-1] != want {
+		t.Errorf("want server started with env %s", want)
+	}
+}
+
+func TestE2EOpenStartsToolchainServer(t *testing.T) {
+	app, ts := newTestApp(t, newFakeDocServer(t), false)
+	defer ts.Close()
+	app.args = []string{"fmt"}
+	app.srv.GOROOT = "/home/me/sdk/go1.21.0"
+
+	var err error
+	captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server is identifiable by its GOROOT
+	starter := app.srv.Starter.(*fakeStarter)
+	p := server.Process{Cmdline: append([]string{"godoc"}, starter.args...)}
+	if !sameGOROOT(p, app.srv.GOROOT) || p.Port() != app.srv.Port {
+		t.Errorf("want server started with GOROOT %s on port %d, but got %v", app.srv.GOROOT, app.srv.Port, starter.args)
+	}
+	if sameGOROOT(p, "") {
+		t.Error("want toolchain server not to match default GOROOT")
+	}
+}
+
+func TestE2EOpenWorkspaceMismatch(t *testing.T) {
+	testCases := []struct {
+		workspace   string
+		wantStarted bool
+	}{
+		{workspace: "github.com/neilotoole/gohdoc", wantStarted: false},
+		{workspace: "github.com/my/mod", wantStarted: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.workspace, func(t *testing.T) {
+			app, ts := newTestApp(t, newFakeDocServer(t), true)
+			defer ts.Close()
+			app.args = []string{"fmt"}
+
+			port := app.srv.Port
+			app.findWorkspace = func(ctx context.Context, dir string) (*resolve.Workspace, error) {
+				return &resolve.Workspace{ImportPath: tc.workspace, Root: "/home/me/mod", Module: true}, nil
+			}
+			// The running server was started in another module
+			app.listProcesses = func(ctx context.Context) ([]server.Process, error) {
+				p := server.Process{PID: 7, Cmdline: []string{"godoc", fmt.Sprintf("-http=:%d", port)}, Dir: "/home/me/other"}
+				return []server.Process{p}, nil
+			}
+
+			var err error
+			captureStdout(t, func() { err = cmdOpen(app) })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			started := app.srv.Proc != nil
+			if started != tc.wantStarted || started == (app.srv.Port == port) {
+				t.Fatalf("want server started on another port: %v, but got port %d (was %d)", tc.wantStarted, app.srv.Port, port)
+			}
+
+			want := app.srv.BaseURL() + "/pkg/fmt/"
+			urls := app.browser.(*fakeBrowser).urls
+			if len(urls) != 1 || urls[0] != want {
+				t.Errorf("want %q opened but got %v", want, urls)
+			}
+		})
+	}
+}
+
+func TestE2EOpenRestart(t *testing.T) {
+	app, ts := newTestApp(t, newFakeDocServer(t), true)
+	defer ts.Close()
+	app.args = []string{"fmt"}
+	app.flagRestart = true
+
+	port := app.srv.Port
+	app.findWorkspace = func(ctx context.Context, dir string) (*resolve.Workspace, error) {
+		return &resolve.Workspace{ImportPath: "github.com/my/mod", Root: "/home/me/mod", Module: true}, nil
+	}
+	// The running server was started in another module
+	app.listProcesses = func(ctx context.Context) ([]server.Process, error) {
+		p := server.Process{PID: 7, Cmdline: []string{"godoc", fmt.Sprintf("-http=:%d", port)}, Dir: "/home/me/other"}
+		return []server.Process{p}, nil
+	}
+	var killed []int32
+	client := app.srv.Client.(*fakeClient)
+	app.killProcess = func(ctx context.Context, p server.Process) error {
+		killed = append(killed, p.PID)
+		client.setDown(strconv.Itoa(p.Port()))
+		return nil
+	}
+
+	var err error
+	captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(killed) != 1 || killed[0] != 7 {
+		t.Errorf("want server [7] killed but got %v", killed)
+	}
+	// The server is restarted in its place
+	if app.srv.Proc == nil || app.srv.Port != port {
+		t.Fatalf("want server started on port %d, but got port %d (started: %v)", port, app.srv.Port, app.srv.Proc != nil)
+	}
+
+	want := app.srv.BaseURL() + "/pkg/fmt/"
+	urls := app.browser.(*fakeBrowser).urls
+	if len(urls) != 1 || urls[0] != want {
+		t.Errorf("want %q opened but got %v", want, urls)
+	}
+}
+
+func TestE2EServers(t *testing.T) {
+	app := &App{srv: &server.Server{}, ctx: context.Background()}
+	app.listProcesses = func(ctx context.Context) ([]server.Process, error) {
+		return []server.Process{
+			{PID: 7, Username: "me", Cmdline: []string{"godoc", "-http=localhost:6060"}},
+			{PID: 8, Username: "me", Cmdline: []string{"godoc", "-http=:6061"}},
+		}, nil
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = cmdServers(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 servers listed but got:\n%s", out)
+	}
+	if !containsFields(lines[0], "me", "7", "godoc", "-http=localhost:6060") {
+		t.Errorf("want loopback server 7 listed without warning but got %q", lines[0])
+	}
+	if !strings.HasPrefix(strings.Join(strings.Fields(lines[1]), " "), "me 8 godoc -http=:6061 [WARNING:") {
+		t.Errorf("want server 8 listed with warning but got %q", lines[1])
+	}
+}
+
+func TestE2EOpenExplain(t *testing.T) {
+	app, ts := newTestApp(t, newFakeDocServer(t), true)
+	defer ts.Close()
+	app.args = []string{"json"}
+	app.flagExplain = true
+
+	var err error
+	out := captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Inputs:\n  cwd:",
+		"  1. path: no pkg matches a trailing part of path /go/src/github.com/neilotoole/gohdoc/json\n",
+		"possible matches: search term json matches 2 pkg(s)\n",
+		"  HEAD " + app.srv.BaseURL() + "/pkg/encoding/json/ ",
+		"  attempt 1  200  ",
+		"Decision:\n  encoding/json\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want explanation to contain %q but got:\n%s", want, out)
+		}
+	}
+
+	// The worse-ranked candidate's page may not be verified, as the
+	// best-ranked candidate's page is found OK first
+	for _, want := range [][]string{
+		{"SCORE", "MATCH", "PAGE", "PKG"},
+		{"3", "suffix", "ok", "encoding/json"},
+	} {
+		if !containsFields(out, want...) {
+			t.Errorf("want explanation to contain line %v but got:\n%s", want, out)
+		}
+	}
+	if !containsFields(out, "1", "contains", "ok", "net/rpc/jsonrpc") &&
+		!containsFields(out, "1", "contains", "not", "tried", "net/rpc/jsonrpc") {
+		t.Errorf("want explanation to contain net/rpc/jsonrpc candidate but got:\n%s", out)
+	}
+
+	// The explanation is also printed on failure
+	app.args = []string{"nope/nope"}
+	app.trace, app.probes = nil, nil
+	out = captureStdout(t, func() { err = cmdOpen(app) })
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(out, "Decision:\n  failed: ") || !strings.Contains(out, "Probes:\n  none\n") {
+		t.Errorf("want failed decision without probes but got:\n%s", out)
+	}
+}
+
+func TestE2ESearch(t *testing.T) {
+	// The server isn't required to search
+	app, ts := newTestApp(t, newFakeDocServer(t), false)
+	defer ts.Close()
+	app.args = []string{"json"}
+
+	var err error
+	out := captureStdout(t, func() { err = cmdSearch(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "encoding/json\nnet/rpc/jsonrpc\n"
+	if out != want {
+		t.Errorf("want %q but got %q", want, out)
+	}
+	if app.srv.Proc != nil {
+		t.Error("should not have started a server")
+	}
+}
+
+func TestE2EList(t *testing.T) {
+	app, ts := newTestApp(t, newFakeDocServer(t), false)
+	defer ts.Close()
+
+	var err error
+	out := captureStdout(t, func() { err = cmdList(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The commands are listed separately, after the pkgs
+	const wantCmds = "\nCommands:\ncmd/vet\ngithub.com/my/tool\n"
+	if !strings.HasSuffix(out, wantCmds) || !strings.HasPrefix(out, "archive\narchive/tar\n") {
+		t.Errorf("want pkgs then %q but got %q", wantCmds, out)
+	}
+}
+
+func TestE2ERemote(t *testing.T) {
+	const token = "s3cret"
+	ds := newFakeDocServer(t)
+	// The remote server is behind a path prefix, and requires bearer auth
+	remote := http.StripPrefix("/docs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ds.ServeHTTP(w, r)
+	}))
+
+	app, ts := newTestApp(t, ds, false)
+	defer ts.Close()
+	rs := httptest.NewServer(remote)
+	defer rs.Close()
+
+	var err error
+	app.srv.URL, app.srv.Auth, err = server.ParseURL(rs.URL + "/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.srv.Client = http.DefaultClient
+	app.args = []string{"fmt#Println"}
+
+	// Without the token, gohdoc should fail, rather than start a local server
+	captureStdout(t, func() { err = cmdOpen(app) })
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if app.srv.Proc != nil {
+		t.Fatal("should not have started a server")
+	}
+
+	app.srv.Auth.Token = token
+	captureStdout(t, func() { err = cmdOpen(app) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := rs.URL + "/docs/pkg/fmt/#Println"
+	urls := app.browser.(*fakeBrowser).urls
+	if len(urls) != 1 || urls[0] != want {
+		t.Errorf("want %q opened but got %v", want, urls)
+	}
+}
+
+// containsFields returns true if a line of out consists of fields.
+func containsFields(out string, fields ...string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		if reflect.DeepEqual(strings.Fields(line), fields) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// printExplanation prints the -explain resolution trace of the pkg arg:
+// the inputs, each strategy tried with its candidate pkgs, the probes
+// of the server's pkg pages, and the final decision (res, or err).
+func printExplanation(app *App, w io.Writer, res *resolve.Result, err error) {
+	tr := app.trace
+	if tr == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "Inputs:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  cwd:\t%s\n", app.cwd)
+	fmt.Fprintf(tw, "  args:\t%s\n", strings.Join(app.args, " "))
+	fmt.Fprintf(tw, "  path:\t%s\n", tr.Arg.Path)
+	fmt.Fprintf(tw, "  pkg:\t%s\n", tr.Arg.Pkg)
+	fmt.Fprintf(tw, "  fragment:\t%s\n", tr.Arg.Fragment)
+	fmt.Fprintf(tw, "  symbol:\t%s\n", tr.Arg.Symbol)
+	fmt.Fprintf(tw, "  server:\t%s\n", app.srv.BaseURL())
+	_ = tw.Flush()
+
+	fmt.Fprintln(w, "\nStrategies:")
+	for i, step := range tr.Steps {
+		fmt.Fprintf(w, "  %d. %s: %s\n", i+1, step.Strategy, step.Note)
+		if len(step.Candidates) == 0 {
+			continue
+		}
+
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "       SCORE\tMATCH\tPAGE\tPKG")
+		for _, c := range step.Candidates {
+			page := "not tried"
+			if c.Err != nil {
+				page = "error"
+			}
+			if c.Verified {
+				page = "not found"
+				if c.PageOK {
+					page = "ok"
+				}
+			}
+			fmt.Fprintf(tw, "       %d\t%s\t%s\t%s\n", c.Score, c.Match, page, c.Pkg)
+		}
+		_ = tw.Flush()
+	}
+
+	fmt.Fprintln(w, "\nProbes:")
+	if len(app.probes) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, p := range app.probes {
+		status := fmt.Sprint(p.Status)
+		if p.Err != nil {
+			status = p.Err.Error()
+		}
+		fmt.Fprintf(tw, "  HEAD %s\tattempt %d\t%s\t%s\n", p.URL, p.Attempt, status, p.Duration)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w, "\nDecision:")
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "  failed: %v\n", err)
+	case res.Fragment != "":
+		fmt.Fprintf(w, "  %s#%s\n", res.Pkg, res.Fragment)
+	default:
+		fmt.Fprintf(w, "  %s\n", res.Pkg)
+	}
+	fmt.Fprintln(w)
+}
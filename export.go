@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/export"
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// cmdExport exports the godoc of each server pkg that matches the pkg
+// pattern args (or all pkgs, if there are no args) as static HTML to
+// dir app.flagExport (see export.Export).
+func cmdExport(app *App) error {
+	err := loadServerPkgList(app)
+	if err != nil {
+		return err
+	}
+
+	pkgs := resolve.Filter(app.serverPkgList, app.args)
+	if len(pkgs) == 0 {
+		return fmt.Errorf("no pkg matches [%s]", strings.Join(app.args, " "))
+	}
+	log.Printf("exporting %d pkgs to %s", len(pkgs), app.flagExport)
+
+	err = export.Export(app.ctx, app.srv, app.srv.BaseURL(), app.flagExport, pkgs, func(pkg string) {
+		fmt.Println(pkg)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d pkgs to %s\n", len(pkgs), filepath.Join(app.flagExport, "pkg", "index.html"))
+	return nil
+}
@@ -0,0 +1,276 @@
+// Package export exports godoc http server pages as static HTML.
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+// Export exports the godoc of each of pkgs, as served by the godoc http
+// server at baseURL (e.g. "http://localhost:6060") via client, as static
+// HTML to dir. The pkg list page is exported to dir/pkg/index.html, and
+// each pkg to dir/pkg/<pkg>/index.html. Links between exported pages are
+// rewritten to be relative, and the CSS/JS assets used by the pages are
+// copied, so that the output can be served by any web server (or viewed
+// from file). If onPkg is non-nil, it is invoked after each pkg is
+// exported.
+func Export(ctx context.Context, client server.HTTPClient, baseURL, dir string, pkgs []string, onPkg func(pkg string)) error {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+
+	ex := &exporter{
+		dir:     dir,
+		baseURL: baseURL,
+		base:    base,
+		client:  client,
+		pkgs:    map[string]bool{},
+		assets:  map[string]bool{},
+	}
+	for _, pkg := range pkgs {
+		ex.pkgs[pkg] = true
+	}
+
+	err = ex.exportPage(ctx, "/pkg/")
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		err = ex.exportPage(ctx, "/pkg/"+pkg+"/")
+		if err != nil {
+			return err
+		}
+		if onPkg != nil {
+			onPkg(pkg)
+		}
+	}
+
+	return ex.exportAssets(ctx)
+}
+
+// exporter holds the state of an Export invocation.
+type exporter struct {
+	// dir is the output dir.
+	dir string
+	// baseURL is the godoc http server URL, e.g. "http://localhost:6060".
+	baseURL string
+	// base is baseURL, parsed. A remote server's base URL may have
+	// a path prefix, e.g. "https://godoc.example.com/docs".
+	base *url.URL
+	// client sends the requests to the godoc http server.
+	client server.HTTPClient
+	// pkgs is the set of pkgs being exported.
+	pkgs map[string]bool
+	// assets is the set of server paths of assets (CSS, JS, images)
+	// referenced by exported pages.
+	assets map[string]bool
+}
+
+// exportFile returns the file path (relative to the output dir, using
+// forward slash) that the server page at urlPath is exported to. If the
+// page is not exported, ok is false.
+func (ex *exporter) exportFile(urlPath string) (file string, ok bool) {
+	if urlPath == "/pkg/" || urlPath == "/pkg" {
+		return "pkg/index.html", true
+	}
+
+	if strings.HasPrefix(urlPath, "/pkg/") {
+		pkg := strings.Trim(strings.TrimPrefix(urlPath, "/pkg/"), "/")
+		if ex.pkgs[pkg] {
+			return "pkg/" + pkg + "/index.html", true
+		}
+	}
+
+	return "", false
+}
+
+// serverPath returns the path of u relative to the server's base URL,
+// e.g. "/pkg/fmt/" for "https://godoc.example.com/docs/pkg/fmt/". If u
+// isn't a page of the server, ok is false.
+func (ex *exporter) serverPath(u *url.URL) (urlPath string, ok bool) {
+	prefix := strings.TrimSuffix(ex.base.Path, "/")
+	if u.Host != ex.base.Host || !strings.HasPrefix(u.Path, prefix+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(u.Path, prefix), true
+}
+
+// exportPage fetches the server page at urlPath, rewrites its links
+// and writes it to the output dir.
+func (ex *exporter) exportPage(ctx context.Context, urlPath string) error {
+	file, _ := ex.exportFile(urlPath)
+
+	body, err := ex.fetch(ctx, urlPath)
+	if err != nil {
+		return err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", urlPath, err)
+	}
+
+	pageURL, err := url.Parse(ex.baseURL + urlPath)
+	if err != nil {
+		return err
+	}
+
+	ex.rewriteLinks(doc, pageURL, file)
+	ex.rewriteAssets(doc, pageURL, file)
+
+	html, err := goquery.OuterHtml(doc.Selection)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %v", urlPath, err)
+	}
+
+	return ex.writeFile(file, []byte(html))
+}
+
+// rewriteLinks rewrites the links of a page (exported to file) that
+// point to other exported pages, so that they are relative. Links to
+// other server pages (e.g. pkgs that aren't exported, or /src/ pages)
+// can't work in the export, so they are removed.
+func (ex *exporter) rewriteLinks(doc *goquery.Document, pageURL *url.URL, file string) {
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if strings.HasPrefix(href, "#") {
+			// Link to fragment on same page
+			return
+		}
+
+		u, err := pageURL.Parse(href)
+		if err != nil {
+			return
+		}
+		urlPath, ok := ex.serverPath(u)
+		if !ok {
+			// Leave external links as they are
+			return
+		}
+
+		target, ok := ex.exportFile(urlPath)
+		if !ok {
+			if row := s.Closest(".pkg-dir tr"); row.Length() > 0 && strings.HasPrefix(urlPath, "/pkg/") {
+				// The pkg list row for a pkg that isn't exported
+				row.Remove()
+				return
+			}
+			s.RemoveAttr("href")
+			return
+		}
+
+		rel := relExportPath(file, target)
+		if u.Fragment != "" {
+			rel += "#" + u.Fragment
+		}
+		s.SetAttr("href", rel)
+	})
+}
+
+// rewriteAssets rewrites the asset (CSS, JS, image) references of a
+// page (exported to file) to be relative, and records the assets to
+// be exported.
+func (ex *exporter) rewriteAssets(doc *goquery.Document, pageURL *url.URL, file string) {
+	rewrite := func(attr string) func(int, *goquery.Selection) {
+		return func(_ int, s *goquery.Selection) {
+			v, _ := s.Attr(attr)
+			u, err := pageURL.Parse(v)
+			if err != nil {
+				return
+			}
+			urlPath, ok := ex.serverPath(u)
+			if !ok {
+				return
+			}
+
+			ex.assets[urlPath] = true
+			s.SetAttr(attr, relExportPath(file, strings.TrimPrefix(urlPath, "/")))
+		}
+	}
+
+	doc.Find(`link[rel="stylesheet"][href]`).Each(rewrite("href"))
+	doc.Find("script[src]").Each(rewrite("src"))
+	doc.Find("img[src]").Each(rewrite("src"))
+}
+
+// exportAssets fetches each asset recorded by rewriteAssets, and writes
+// it to the output dir.
+func (ex *exporter) exportAssets(ctx context.Context) error {
+	var assets []string
+	for asset := range ex.assets {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	for _, asset := range assets {
+		body, err := ex.fetch(ctx, asset)
+		if err != nil {
+			return err
+		}
+
+		err = ex.writeFile(strings.TrimPrefix(asset, "/"), body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch returns the body of the server page at urlPath.
+func (ex *exporter) fetch(ctx context.Context, urlPath string) ([]byte, error) {
+	u := ex.baseURL + urlPath
+	log.Printf("fetching %s", u)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ex.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access godoc http server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %s from %s", resp.Status, u)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body from %s: %v", u, err)
+	}
+	return body, nil
+}
+
+// writeFile writes data to file (a forward slash path relative to
+// the output dir), creating parent dirs as necessary.
+func (ex *exporter) writeFile(file string, data []byte) error {
+	fp := filepath.Join(ex.dir, filepath.FromSlash(file))
+	err := os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, data, 0644)
+}
+
+// relExportPath returns the relative link from exported file from
+// to exported file to. Both args are relative to the output dir.
+func relExportPath(from, to string) string {
+	return strings.Repeat("../", strings.Count(path.Clean(from), "/")) + to
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRelExportPath(t *testing.T) {
+	testCases := []struct {
+		from, to, want string
+	}{
+		{from: "pkg/index.html", to: "pkg/fmt/index.html", want: "../pkg/fmt/index.html"},
+		{from: "pkg/fmt/index.html", to: "pkg/index.html", want: "../../pkg/index.html"},
+		{from: "pkg/net/http/index.html", to: "lib/godoc/style.css", want: "../../../lib/godoc/style.css"},
+	}
+
+	for _, tc := range testCases {
+		got := relExportPath(tc.from, tc.to)
+		if got != tc.want {
+			t.Errorf("relExportPath(%q, %q): want %q but got %q", tc.from, tc.to, tc.want, got)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	pkgPage, err := ioutil.ReadFile("../testdata/pkg_1.11.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/pkg/":
+			_, _ = w.Write(pkgPage)
+		case strings.HasPrefix(r.URL.Path, "/pkg/"):
+			_, _ = w.Write([]byte(`<html><head><link rel="stylesheet" href="/lib/godoc/style.css"></head>
+<body><a href="/pkg/">index</a> <a href="/pkg/bytes/#Buffer">bytes</a> <a href="/pkg/fmt/#Println">fmt</a>
+<a href="#Println">Println</a> <a href="/src/fmt/print.go">src</a> <a href="https://golang.org/">ext</a></body></html>`))
+		default:
+			_, _ = w.Write([]byte("asset " + r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "gohdoc_export_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var exported []string
+	err = Export(context.Background(), http.DefaultClient, srv.URL, dir, []string{"fmt"}, func(pkg string) {
+		exported = append(exported, pkg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 1 || exported[0] != "fmt" {
+		t.Errorf("want exported [fmt] but got %v", exported)
+	}
+
+	read := func(file string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	index := read("pkg/index.html")
+	if !strings.Contains(index, `href="fmt/index.html"`) && !strings.Contains(index, `href="../pkg/fmt/index.html"`) {
+		t.Error("index should link to exported pkg fmt")
+	}
+	if strings.Contains(index, `bytes/`) {
+		t.Error("index should not list pkg bytes, which was not exported")
+	}
+	if !strings.Contains(index, `href="../lib/godoc/style.css"`) {
+		t.Error("index should link to exported style.css")
+	}
+
+	fmtPage := read("pkg/fmt/index.html")
+	for _, want := range []string{
+		`<a href="../../pkg/index.html">index</a>`,
+		`<a>bytes</a>`,
+		`<a href="../../pkg/fmt/index.html#Println">fmt</a>`,
+		`<a href="#Println">Println</a>`,
+		`<a>src</a>`,
+		`<a href="https://golang.org/">ext</a>`,
+		`href="../../lib/godoc/style.css"`,
+	} {
+		if !strings.Contains(fmtPage, want) {
+			t.Errorf("exported fmt page should contain %s", want)
+		}
+	}
+
+	if got := read("lib/godoc/style.css"); got != "asset /lib/godoc/style.css" {
+		t.Errorf("unexpected style.css content: %s", got)
+	}
+	if got := read("lib/godoc/godocs.js"); got != "asset /lib/godoc/godocs.js" {
+		t.Errorf("unexpected godocs.js content: %s", got)
+	}
+}
+
+func TestExportPathPrefix(t *testing.T) {
+	// A remote server whose base URL has a path prefix, e.g. a godoc
+	// server behind a reverse proxy at https://godoc.example.com/docs
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs/pkg/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/docs/pkg/" {
+			_, _ = w.Write([]byte(`<html><head><link rel="stylesheet" href="/docs/lib/godoc/style.css"></head>
+<body><div class="pkg-dir"><table><tr><td><a href="fmt/">fmt</a></td></tr><tr><td><a href="bytes/">bytes</a></td></tr></table></div></body></html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<html><head><link rel="stylesheet" href="../../lib/godoc/style.css"></head>
+<body><a href="/docs/pkg/">index</a> <a href="/pkg/other/">other</a></body></html>`))
+	})
+	mux.HandleFunc("/docs/lib/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("asset " + r.URL.Path))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "gohdoc_export_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = Export(context.Background(), http.DefaultClient, srv.URL+"/docs", dir, []string{"fmt"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(file string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	index := read("pkg/index.html")
+	for _, want := range []string{`<a href="../pkg/fmt/index.html">fmt</a>`, `href="../lib/godoc/style.css"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index should contain %s:\n%s", want, index)
+		}
+	}
+	if strings.Contains(index, "bytes") {
+		t.Error("index should not list pkg bytes, which was not exported")
+	}
+
+	fmtPage := read("pkg/fmt/index.html")
+	for _, want := range []string{`<a href="../../pkg/index.html">index</a>`, `<a href="/pkg/other/">other</a>`, `href="../../lib/godoc/style.css"`} {
+		if !strings.Contains(fmtPage, want) {
+			t.Errorf("exported fmt page should contain %s:\n%s", want, fmtPage)
+		}
+	}
+
+	if got := read("lib/godoc/style.css"); got != "asset /docs/lib/godoc/style.css" {
+		t.Errorf("unexpected style.css content: %s", got)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/lint"
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// cmdLint checks the doc comments of the pkg arg for problems that
+// would cause the godoc to render incorrectly (or not at all). Issues
+// are printed as "file:line: message", and an error is returned if
+// any issue is found, so that gohdoc exits non-zero.
+func cmdLint(app *App) error {
+	if len(app.args) > 1 {
+		return fmt.Errorf("lint command takes maximum one arg, but received %d: [%s]",
+			len(app.args), strings.Join(app.args, " "))
+	}
+
+	bp, _, err := importLocalPkg(app)
+	if err != nil {
+		return err
+	}
+
+	issues, err := lint.Check(app.ctx, bp.Dir, resolve.GoFiles(bp))
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		filename := issue.Pos.Filename
+		if rel, err := filepath.Rel(app.cwd, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			filename = rel
+		}
+		fmt.Printf("%s:%d: %s\n", filename, issue.Pos.Line, issue.Msg)
+	}
+
+	switch len(issues) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("found 1 doc comment issue")
+	default:
+		return fmt.Errorf("found %d doc comment issues", len(issues))
+	}
+}
@@ -0,0 +1,553 @@
+// Package lint checks the doc comments of a pkg for problems that would
+// cause the godoc to render incorrectly (or not at all).
+package lint
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// Issue is a doc comment problem found by Check.
+type Issue struct {
+	Pos token.Position
+	Msg string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.Pos.Filename, i.Pos.Line, i.Msg)
+}
+
+// Check checks the doc comments of the pkg consisting of files
+// (relative to dir). The returned issues are sorted by position.
+func Check(ctx context.Context, dir string, files []string) ([]Issue, error) {
+	l := &linter{
+		fset:    token.NewFileSet(),
+		syms:    map[string]bool{},
+		members: map[string]map[string]bool{},
+		imports: map[string]string{},
+	}
+
+	var astFiles []*ast.File
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(l.fset, filepath.Join(dir, f), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		astFiles = append(astFiles, file)
+		l.collect(file)
+	}
+
+	if len(astFiles) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	l.checkPkgDoc(astFiles)
+	for _, file := range astFiles {
+		l.checkDecls(file)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].Pos, l.issues[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	return l.issues, nil
+}
+
+// linter holds the state of a Check invocation.
+type linter struct {
+	fset *token.FileSet
+	// syms holds the names of the pkg's top-level declarations.
+	syms map[string]bool
+	// members holds, for each type name, the names of its methods and fields.
+	members map[string]map[string]bool
+	// imports maps the names of pkgs imported by the pkg's files
+	// to their import paths.
+	imports map[string]string
+	issues  []Issue
+}
+
+func (l *linter) report(pos token.Pos, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{Pos: l.fset.Position(pos), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) reportLine(filename string, line int, format string, args ...interface{}) {
+	pos := token.Position{Filename: filename, Line: line}
+	l.issues = append(l.issues, Issue{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) addMember(typeName, name string) {
+	if l.members[typeName] == nil {
+		l.members[typeName] = map[string]bool{}
+	}
+	l.members[typeName][name] = true
+}
+
+// collect records the symbols declared and pkgs imported by file,
+// for use in resolving doc links.
+func (l *linter) collect(file *ast.File) {
+	for _, imp := range file.Imports {
+		impPath, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil {
+			l.imports[imp.Name.Name] = impPath
+		} else {
+			l.imports[path.Base(impPath)] = impPath
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				l.addMember(resolve.RecvTypeName(decl.Recv.List[0].Type), decl.Name.Name)
+			} else {
+				l.syms[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					l.syms[spec.Name.Name] = true
+					l.collectTypeMembers(spec)
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						l.syms[ident.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) collectTypeMembers(spec *ast.TypeSpec) {
+	var fields *ast.FieldList
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	default:
+		return
+	}
+
+	for _, field := range fields.List {
+		for _, ident := range field.Names {
+			l.addMember(spec.Name.Name, ident.Name)
+		}
+		if len(field.Names) == 0 {
+			// embedded field
+			l.addMember(spec.Name.Name, resolve.RecvTypeName(field.Type))
+		}
+	}
+}
+
+// checkPkgDoc checks that the pkg has a well-formed pkg comment.
+func (l *linter) checkPkgDoc(files []*ast.File) {
+	var docs []*ast.File
+	for _, file := range files {
+		if file.Doc != nil {
+			docs = append(docs, file)
+		}
+	}
+
+	if len(docs) == 0 {
+		l.report(files[0].Package, "missing package comment")
+		return
+	}
+
+	for _, file := range docs {
+		name := file.Name.Name
+		text := file.Doc.Text()
+		if name != "main" && !strings.HasPrefix(text, "Package "+name+" ") &&
+			!strings.HasPrefix(text, "Package "+name+"\n") {
+			l.report(file.Doc.Pos(), "package comment should be of the form \"Package %s ...\"", name)
+		}
+		l.checkText(file.Doc)
+	}
+}
+
+// checkDecls checks the doc comments of the exported declarations in file.
+func (l *linter) checkDecls(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+
+			kind, name := "func", decl.Name.Name
+			if decl.Recv != nil {
+				recv := resolve.RecvTypeName(decl.Recv.List[0].Type)
+				if !ast.IsExported(recv) {
+					continue
+				}
+				kind, name = "method", recv+"."+decl.Name.Name
+			}
+			l.checkDoc(decl.Doc, decl.Pos(), kind, name, decl.Name.Name, false)
+
+		case *ast.GenDecl:
+			l.checkGenDecl(decl)
+		}
+	}
+}
+
+func (l *linter) checkGenDecl(decl *ast.GenDecl) {
+	if decl.Tok == token.IMPORT {
+		return
+	}
+
+	grouped := decl.Lparen.IsValid()
+	if grouped && decl.Doc != nil {
+		l.checkText(decl.Doc)
+	}
+
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			if !spec.Name.IsExported() {
+				continue
+			}
+
+			doc := spec.Doc
+			if doc == nil && !grouped {
+				doc = decl.Doc
+			}
+			l.checkDoc(doc, spec.Pos(), "type", spec.Name.Name, spec.Name.Name, true)
+
+		case *ast.ValueSpec:
+			kind := decl.Tok.String()
+			for _, ident := range spec.Names {
+				if !ident.IsExported() {
+					continue
+				}
+
+				switch {
+				case spec.Doc != nil:
+					l.checkDoc(spec.Doc, ident.Pos(), kind, ident.Name, ident.Name, false)
+				case !grouped:
+					l.checkDoc(decl.Doc, ident.Pos(), kind, ident.Name, ident.Name, false)
+				case decl.Doc == nil:
+					// A doc comment on the group suffices for its members.
+					l.report(ident.Pos(), "exported %s %s should have comment (or a comment on this block)",
+						kind, ident.Name)
+				}
+
+				if spec.Doc != nil || !grouped {
+					// Only report one issue per spec.
+					break
+				}
+			}
+		}
+	}
+}
+
+// checkDoc checks the doc comment of the exported identifier name.
+// The doc comment is expected to begin with prefix; if articleOK is true,
+// the doc comment may also begin with "A", "An" or "The" followed by prefix.
+func (l *linter) checkDoc(doc *ast.CommentGroup, pos token.Pos, kind, name, prefix string, articleOK bool) {
+	if doc == nil {
+		l.report(pos, "exported %s %s should have comment", kind, name)
+		return
+	}
+
+	text := doc.Text()
+	ok := strings.HasPrefix(text, prefix+" ") || strings.HasPrefix(text, prefix+"\n") ||
+		strings.HasPrefix(text, "Deprecated: ")
+	if !ok && articleOK {
+		for _, article := range []string{"A ", "An ", "The "} {
+			if strings.HasPrefix(text, article+prefix+" ") {
+				ok = true
+				break
+			}
+		}
+	}
+	if !ok {
+		l.report(doc.Pos(), "comment on exported %s %s should be of the form \"%s ...\"", kind, name, prefix)
+	}
+
+	l.checkText(doc)
+}
+
+// commentLine is a line of a doc comment, with the comment
+// markers removed.
+type commentLine struct {
+	text     string
+	filename string
+	line     int
+}
+
+// commentLines returns the lines of cg, excluding directives
+// such as "//go:generate".
+func (l *linter) commentLines(cg *ast.CommentGroup) []commentLine {
+	var lines []commentLine
+	for _, c := range cg.List {
+		pos := l.fset.Position(c.Pos())
+		if strings.HasPrefix(c.Text, "//") {
+			text := c.Text[2:]
+			if isDirective(text) {
+				continue
+			}
+			lines = append(lines, commentLine{text: strings.TrimPrefix(text, " "), filename: pos.Filename, line: pos.Line})
+			continue
+		}
+
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		for i, s := range strings.Split(text, "\n") {
+			lines = append(lines, commentLine{text: strings.TrimPrefix(s, " "), filename: pos.Filename, line: pos.Line + i})
+		}
+	}
+	return lines
+}
+
+var directiveRegex = regexp.MustCompile(`^(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+// isDirective returns true if the text of a "//" comment (without the
+// leading slashes) is a directive such as "go:generate".
+func isDirective(text string) bool {
+	return directiveRegex.MatchString(text)
+}
+
+// checkText checks the formatting of doc comment cg, as parsed by
+// go/doc/comment, and thus as godoc renders it.
+func (l *linter) checkText(cg *ast.CommentGroup) {
+	lines := l.commentLines(cg)
+	if len(lines) == 0 {
+		return
+	}
+
+	texts := make([]string, len(lines))
+	for i, cl := range lines {
+		texts[i] = cl.text
+	}
+
+	p := &comment.Parser{
+		LookupPackage: l.lookupPackage,
+		// Every [Name] or [Recv.Name] is parsed as a doc link, so that
+		// those that don't resolve to a symbol of the pkg can be reported.
+		LookupSym: func(recv, name string) bool { return true },
+	}
+	doc := p.Parse(strings.Join(texts, "\n"))
+
+	c := &textChecker{l: l, lines: lines}
+	for _, block := range doc.Content {
+		c.block(block)
+	}
+}
+
+// lookupPackage implements comment.Parser.LookupPackage, resolving the
+// names of the pkgs imported by the pkg.
+func (l *linter) lookupPackage(name string) (importPath string, ok bool) {
+	importPath, ok = l.imports[name]
+	return importPath, ok
+}
+
+// resolveDocLink returns true if link refers to a symbol of the pkg,
+// or to another pkg. Links to other pkgs are not verified.
+func (l *linter) resolveDocLink(link *comment.DocLink) bool {
+	switch {
+	case link.ImportPath != "":
+		return true
+	case link.Recv != "":
+		return l.members[link.Recv][link.Name]
+	default:
+		return l.syms[link.Name]
+	}
+}
+
+// textChecker checks the blocks of a parsed doc comment, locating
+// each block in the comment's lines, so that issues can be reported
+// at the right line.
+type textChecker struct {
+	l     *linter
+	lines []commentLine
+	// next is the index of the line after the last located block.
+	next int
+}
+
+// locate returns the index of the first line, from c.next on, that ends
+// with text (ignoring surrounding space), and advances c.next past it.
+// Lines are matched by suffix, as the text of a list item doesn't
+// include its marker.
+func (c *textChecker) locate(text string) int {
+	text = strings.TrimSpace(text)
+	for i := c.next; i < len(c.lines); i++ {
+		if strings.HasSuffix(strings.TrimSpace(c.lines[i].text), text) {
+			c.next = i + 1
+			return i
+		}
+	}
+	if c.next < len(c.lines) {
+		return c.next
+	}
+	return len(c.lines) - 1
+}
+
+// line returns the i'th line, or the last line if i is out of range.
+func (c *textChecker) line(i int) commentLine {
+	if i >= len(c.lines) {
+		i = len(c.lines) - 1
+	}
+	return c.lines[i]
+}
+
+func (c *textChecker) report(cl commentLine, format string, args ...interface{}) {
+	c.l.reportLine(cl.filename, cl.line, format, args...)
+}
+
+func (c *textChecker) block(b comment.Block) {
+	switch b := b.(type) {
+	case *comment.Heading:
+		c.locate(flattenText(b.Text))
+	case *comment.Code:
+		codeLines := strings.Split(strings.TrimSuffix(b.Text, "\n"), "\n")
+		c.next = c.locate(codeLines[0]) + len(codeLines)
+	case *comment.List:
+		for _, item := range b.Items {
+			for _, content := range item.Content {
+				c.block(content)
+			}
+		}
+	case *comment.Paragraph:
+		c.paragraph(b.Text)
+	}
+}
+
+// paragraph checks paragraph text: for constructs that were evidently
+// intended as a heading, list or link definition, but which godoc
+// renders as paragraph text; for doc links that don't resolve; and
+// for Deprecated notices.
+func (c *textChecker) paragraph(text []comment.Text) {
+	flat := flattenText(text)
+	paraLines := strings.Split(flat, "\n")
+	start := c.locate(paraLines[0])
+	c.next = start + len(paraLines)
+
+	for i, s := range paraLines {
+		cl := c.line(start + i)
+		switch {
+		case strings.HasPrefix(s, "#") && isHeading("# "+strings.TrimPrefix(s, "#")):
+			if !strings.HasPrefix(s, "# ") {
+				c.report(cl, "heading is missing a space after '#': %q", s)
+			} else if len(paraLines) > 1 {
+				c.report(cl, "heading must be preceded and followed by a blank line: %q", s)
+			}
+		case isListItem(s):
+			c.report(cl, "list item is not indented, and will render as paragraph text: %q", s)
+		case strings.HasPrefix(s, "[") && strings.Contains(s, "]:"):
+			// Not parsed as a link definition, so the URL is invalid
+			j := strings.Index(s, "]:")
+			c.report(cl, "link definition [%s] has invalid URL %q", s[1:j], strings.TrimSpace(s[j+2:]))
+		}
+	}
+
+	line := start
+	for _, t := range text {
+		if link, ok := t.(*comment.DocLink); ok && !c.l.resolveDocLink(link) {
+			name := link.Name
+			if link.Recv != "" {
+				name = link.Recv + "." + name
+			}
+			c.report(c.line(line), "doc link [%s] does not resolve", name)
+		}
+		line += strings.Count(flattenText([]comment.Text{t}), "\n")
+	}
+
+	if i := strings.Index(flat, "Deprecated:"); i >= 0 {
+		cl := c.line(start + strings.Count(flat[:i], "\n"))
+		if i > 0 {
+			c.report(cl, "Deprecated notice should be a paragraph of its own")
+		}
+		if !suggestsAlternative(text, flat[i+len("Deprecated:"):]) {
+			c.report(cl, "Deprecated notice does not suggest an alternative")
+		}
+	}
+}
+
+// flattenText returns the source text of text: links are enclosed
+// in square brackets, as they are in the doc comment.
+func flattenText(text []comment.Text) string {
+	var sb strings.Builder
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(string(t))
+		case comment.Italic:
+			sb.WriteString(string(t))
+		case *comment.Link:
+			if t.Auto {
+				sb.WriteString(flattenText(t.Text))
+			} else {
+				sb.WriteString("[" + flattenText(t.Text) + "]")
+			}
+		case *comment.DocLink:
+			sb.WriteString("[" + flattenText(t.Text) + "]")
+		}
+	}
+	return sb.String()
+}
+
+// isHeading returns true if line, as a paragraph of its own, would be
+// parsed as a heading.
+func isHeading(line string) bool {
+	doc := new(comment.Parser).Parse(line)
+	if len(doc.Content) != 1 {
+		return false
+	}
+	_, ok := doc.Content[0].(*comment.Heading)
+	return ok
+}
+
+// isListItem returns true if line, were it indented, would be parsed
+// as a list item.
+func isListItem(line string) bool {
+	// Parse unindents the text, so the indented line must be preceded
+	// by an unindented paragraph.
+	doc := new(comment.Parser).Parse("Text.\n\n  " + line)
+	if len(doc.Content) != 2 {
+		return false
+	}
+	_, ok := doc.Content[1].(*comment.List)
+	return ok
+}
+
+// suggestsAlternative returns true if the text of a Deprecated notice
+// (in paragraph text) suggests an alternative: a link, or a word such
+// as "use" or "instead".
+func suggestsAlternative(text []comment.Text, notice string) bool {
+	for _, t := range text {
+		switch t.(type) {
+		case *comment.Link, *comment.DocLink:
+			return true
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(notice), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		switch w {
+		case "use", "instead", "replaced", "replacement", "see":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package lint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	issues, err := Check(context.Background(), "../testdata/lint", []string{"lint.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"lint.go:1: missing package comment",
+		"lint.go:15: exported func Undocumented should have comment",
+		"lint.go:17: comment on exported func BadPrefix should be of the form \"BadPrefix ...\"",
+		"lint.go:26: doc link [Documented.Missing] does not resolve",
+		"lint.go:33: heading is missing a space after '#': \"#Heading\"",
+		"lint.go:35: list item is not indented, and will render as paragraph text: \"- unindented list item\"",
+		"lint.go:37: doc link [Nowhere] does not resolve",
+		"lint.go:39: link definition [bad link] has invalid URL \"not-a-url\"",
+		"lint.go:44: Deprecated notice does not suggest an alternative",
+		"lint.go:61: exported var Bare should have comment (or a comment on this block)",
+		"lint.go:71: heading must be preceded and followed by a blank line: \"# Heading\"",
+		"lint.go:73: doc link [Missing] does not resolve",
+	}
+
+	var got []string
+	for _, issue := range issues {
+		issue.Pos.Filename = filepath.Base(issue.Pos.Filename)
+		got = append(got, issue.String())
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("want %d issues but got %d", len(want), len(got))
+	}
+	for i := 0; i < len(want) && i < len(got); i++ {
+		if got[i] != want[i] {
+			t.Errorf("issue %d:\nwant: %s\n got: %s", i, want[i], got[i])
+		}
+	}
+	for i := len(want); i < len(got); i++ {
+		t.Errorf("unexpected issue: %s", got[i])
+	}
+}
+
+func TestCheckExample(t *testing.T) {
+	issues, err := Check(context.Background(), "../testdata/example", []string{"example.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Errorf("unexpected issue: %s", issue)
+	}
+}
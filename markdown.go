@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/markdown"
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// cmdMarkdown prints a Markdown rendering of the godoc of the pkg arg
+// (see markdown.Render).
+func cmdMarkdown(app *App) error {
+	if len(app.args) > 1 {
+		return fmt.Errorf("markdown command takes maximum one arg, but received %d: [%s]",
+			len(app.args), strings.Join(app.args, " "))
+	}
+
+	bp, _, err := importLocalPkg(app)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	files = append(files, resolve.GoFiles(bp)...)
+	files = append(files, bp.TestGoFiles...)
+	files = append(files, bp.XTestGoFiles...)
+
+	md, err := markdown.Render(app.ctx, bp.Dir, files, bp.ImportPath, loadAPIVersions(app))
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(md))
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package markdown renders the godoc of a pkg as Markdown.
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// Render returns a Markdown rendering of the godoc of the pkg with
+// importPath, consisting of files (relative to dir). Any test files are
+// used only for their examples. If versions is non-nil, std lib symbols
+// are annotated with the Go version in which they were added.
+//
+// The Markdown has an anchor (an HTML <a> element with an id) for each
+// symbol, using the same names as godoc's fragments, e.g. #Println or
+// #Buffer.Len, so that gohdoc-style fragments can be used to link to
+// the rendered Markdown.
+func Render(ctx context.Context, dir string, files []string, importPath string, versions *resolve.APIVersions) ([]byte, error) {
+	fset := token.NewFileSet()
+	var astFiles []*ast.File
+	var comments []*ast.CommentGroup
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, f), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		astFiles = append(astFiles, file)
+		comments = append(comments, file.Comments...)
+	}
+
+	pkg, err := doc.NewFromFiles(fset, astFiles, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &renderer{fset: fset, comments: comments, pkg: pkg, versions: versions}
+	r.render()
+	return r.buf.Bytes(), nil
+}
+
+// renderer holds the state of a Render invocation.
+type renderer struct {
+	buf      bytes.Buffer
+	fset     *token.FileSet
+	comments []*ast.CommentGroup
+	pkg      *doc.Package
+	versions *resolve.APIVersions
+}
+
+func (r *renderer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&r.buf, format, args...)
+}
+
+// heading prints a Markdown heading, preceded by an anchor with id.
+func (r *renderer) heading(level int, id, text string) {
+	r.printf("<a id=%q></a>\n%s %s\n\n", id, strings.Repeat("#", level), text)
+}
+
+// addedIn prints the Go version in which the std lib symbol with
+// godoc fragment id (or the pkg, if id is empty) was added, if known.
+func (r *renderer) addedIn(id string) {
+	if v := r.versions.AddedIn(r.pkg.ImportPath, id); v != "" {
+		r.printf("*Added in %s*\n\n", v)
+	}
+}
+
+func (r *renderer) render() {
+	pkg := r.pkg
+	r.printf("# package %s\n\n", pkg.Name)
+	r.printf("```go\nimport %q\n```\n\n", pkg.ImportPath)
+	r.addedIn("")
+
+	r.heading(2, "pkg-overview", "Overview")
+	r.docText(pkg.Doc)
+	r.examples(pkg.Examples)
+
+	r.index()
+
+	if len(pkg.Consts) > 0 {
+		r.heading(2, "pkg-constants", "Constants")
+		r.values(pkg.Consts)
+	}
+	if len(pkg.Vars) > 0 {
+		r.heading(2, "pkg-variables", "Variables")
+		r.values(pkg.Vars)
+	}
+
+	for _, fn := range pkg.Funcs {
+		r.fn(fn, "")
+	}
+
+	for _, typ := range pkg.Types {
+		r.heading(2, typ.Name, "type "+typ.Name)
+		r.addedIn(typ.Name)
+		r.code(typ.Decl)
+		r.docText(typ.Doc)
+		r.examples(typ.Examples)
+		r.values(typ.Consts)
+		r.values(typ.Vars)
+		for _, fn := range typ.Funcs {
+			r.fn(fn, "")
+		}
+		for _, fn := range typ.Methods {
+			r.fn(fn, typ.Name)
+		}
+	}
+}
+
+// index prints the pkg index: a list of links to each symbol.
+func (r *renderer) index() {
+	pkg := r.pkg
+	r.heading(2, "pkg-index", "Index")
+
+	if len(pkg.Consts) > 0 {
+		r.printf("- [Constants](#pkg-constants)\n")
+	}
+	if len(pkg.Vars) > 0 {
+		r.printf("- [Variables](#pkg-variables)\n")
+	}
+	for _, fn := range pkg.Funcs {
+		r.printf("- [%s](#%s)\n", escape(r.signature(fn.Decl)), fn.Name)
+	}
+	for _, typ := range pkg.Types {
+		r.printf("- [type %s](#%s)\n", typ.Name, typ.Name)
+		for _, fn := range typ.Funcs {
+			r.printf("  - [%s](#%s)\n", escape(r.signature(fn.Decl)), fn.Name)
+		}
+		for _, fn := range typ.Methods {
+			r.printf("  - [%s](#%s.%s)\n", escape(r.signature(fn.Decl)), typ.Name, fn.Name)
+		}
+	}
+	r.printf("\n")
+
+	examples := allExamples(pkg)
+	if len(examples) > 0 {
+		r.heading(3, "pkg-examples", "Examples")
+		for _, ex := range examples {
+			r.printf("- [%s](#%s)\n", ex.title, ex.id)
+		}
+		r.printf("\n")
+	}
+}
+
+// fn prints the doc for func (or method, if recv is non-empty) fn.
+func (r *renderer) fn(fn *doc.Func, recv string) {
+	id, title := fn.Name, "func "+fn.Name
+	if recv != "" {
+		id = recv + "." + fn.Name
+		title = fmt.Sprintf("func (%s) %s", fn.Recv, fn.Name)
+	}
+
+	r.heading(3, id, escape(title))
+	r.addedIn(id)
+	r.code(fn.Decl)
+	r.docText(fn.Doc)
+	r.examples(fn.Examples)
+}
+
+// values prints the doc for a set of const or var declarations.
+func (r *renderer) values(values []*doc.Value) {
+	for _, v := range values {
+		r.code(v.Decl)
+		r.docText(v.Doc)
+	}
+}
+
+// examples prints each example, headed by an anchor compatible
+// with godoc's example fragments, e.g. #example_Println.
+func (r *renderer) examples(examples []*doc.Example) {
+	for _, ex := range examples {
+		id, title := exampleIDTitle(ex)
+		r.heading(4, id, title)
+		r.docText(ex.Doc)
+		r.printf("```go\n%s\n```\n\n", r.exampleCode(ex))
+		if ex.Output != "" {
+			r.printf("Output:\n\n```\n%s```\n\n", ex.Output)
+		}
+	}
+}
+
+// docText prints doc comment text as Markdown.
+func (r *renderer) docText(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	p := r.pkg.Printer()
+	p.HeadingLevel = 4
+	p.DocLinkBaseURL = "https://pkg.go.dev"
+
+	r.buf.Write(p.Markdown(r.pkg.Parser().Parse(text)))
+	r.printf("\n")
+}
+
+// code prints node as a fenced Go code block.
+func (r *renderer) code(node ast.Node) {
+	r.printf("```go\n%s\n```\n\n", r.sprintNode(node))
+}
+
+// signature returns the one-line signature of a func or method.
+func (r *renderer) signature(decl *ast.FuncDecl) string {
+	d := *decl
+	d.Body = nil
+	d.Doc = nil
+
+	var buf bytes.Buffer
+	_ = (&printer.Config{Mode: printer.RawFormat}).Fprint(&buf, r.fset, &d)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// sprintNode returns the formatted source of node, including any
+// comments within it (e.g. struct field comments).
+func (r *renderer) sprintNode(node ast.Node) string {
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	_ = cfg.Fprint(&buf, r.fset, &printer.CommentedNode{Node: node, Comments: r.comments})
+	return buf.String()
+}
+
+// exampleCode returns the body of example ex, without the braces
+// of the example func.
+func (r *renderer) exampleCode(ex *doc.Example) string {
+	code := r.sprintNode(ex.Code)
+	if _, ok := ex.Code.(*ast.BlockStmt); !ok {
+		return code
+	}
+
+	code = strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}")
+	lines := strings.Split(strings.Trim(code, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escape escapes the Markdown emphasis and link characters in s.
+func escape(s string) string {
+	return strings.NewReplacer("*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// exampleIDTitle returns the godoc fragment id and title for example ex.
+// For example, ExampleBuffer_Len has id "example_Buffer_Len" and title
+// "Example (Buffer.Len)".
+func exampleIDTitle(ex *doc.Example) (id, title string) {
+	// ex.Name is the example func name with the "Example" prefix removed,
+	// e.g. "Buffer_Len". The suffix (if any) is lower-case, and follows
+	// the last underscore.
+	id = "example_" + ex.Name
+	name := ex.Name
+	if ex.Suffix != "" {
+		name = strings.TrimSuffix(name, "_"+ex.Suffix)
+	}
+	name = strings.Replace(name, "_", ".", -1)
+
+	switch {
+	case name == "" && ex.Suffix == "":
+		title = "Example"
+	case name == "":
+		title = fmt.Sprintf("Example (%s)", ex.Suffix)
+	case ex.Suffix == "":
+		title = fmt.Sprintf("Example (%s)", name)
+	default:
+		title = fmt.Sprintf("Example (%s, %s)", name, ex.Suffix)
+	}
+	return id, title
+}
+
+type exampleRef struct {
+	id    string
+	title string
+}
+
+// allExamples returns a ref to each of pkg's examples, in the order in
+// which they appear in the Markdown.
+func allExamples(pkg *doc.Package) []exampleRef {
+	var refs []exampleRef
+	add := func(examples []*doc.Example) {
+		for _, ex := range examples {
+			id, title := exampleIDTitle(ex)
+			refs = append(refs, exampleRef{id: id, title: title})
+		}
+	}
+
+	add(pkg.Examples)
+	for _, fn := range pkg.Funcs {
+		add(fn.Examples)
+	}
+	for _, typ := range pkg.Types {
+		add(typ.Examples)
+		for _, fn := range typ.Funcs {
+			add(fn.Examples)
+		}
+		for _, fn := range typ.Methods {
+			add(fn.Examples)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"context"
+	"go/doc"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+func TestRender(t *testing.T) {
+	files := []string{"example.go", "example_test.go"}
+	md, err := Render(context.Background(), "../testdata/example", files, "github.com/neilotoole/gohdoc/testdata/example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(md)
+
+	for _, want := range []string{
+		"# package example\n",
+		"import \"github.com/neilotoole/gohdoc/testdata/example\"\n",
+		"<a id=\"pkg-overview\"></a>\n## Overview\n\nPackage example is used by gohdoc tests",
+		"<a id=\"pkg-index\"></a>\n## Index\n",
+		"- [Constants](#pkg-constants)\n",
+		"  - [func (g \\*Greeter) Greet(who string) string](#Greeter.Greet)\n",
+		"<a id=\"pkg-constants\"></a>\n## Constants\n\n```go\nconst Greeting = \"hello\"\n```\n",
+		"<a id=\"Greeter\"></a>\n## type Greeter\n",
+		"\t// Name is the name of the greeter.\n\tName string\n",
+		"<a id=\"NewGreeter\"></a>\n### func NewGreeter\n",
+		"<a id=\"Greeter.Greet\"></a>\n### func (\\*Greeter) Greet\n",
+		"<a id=\"example_NewGreeter\"></a>\n#### Example (NewGreeter)\n",
+		"Output:\n\n```\nhello world, from gohdoc\n```\n",
+		"- [Example (Greeter.Greet, formal)](#example_Greeter_Greet_formal)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown should contain %q", want)
+		}
+	}
+
+	if strings.Contains(got, "greet()") {
+		t.Error("markdown should not contain unexported func greet")
+	}
+}
+
+func TestExampleIDTitle(t *testing.T) {
+	testCases := []struct {
+		name, suffix string
+		wantID       string
+		wantTitle    string
+	}{
+		{name: "", wantID: "example_", wantTitle: "Example"},
+		{name: "_basic", suffix: "basic", wantID: "example__basic", wantTitle: "Example (basic)"},
+		{name: "Println", wantID: "example_Println", wantTitle: "Example (Println)"},
+		{name: "Buffer_Len", wantID: "example_Buffer_Len", wantTitle: "Example (Buffer.Len)"},
+		{name: "Buffer_Len_zero", suffix: "zero", wantID: "example_Buffer_Len_zero", wantTitle: "Example (Buffer.Len, zero)"},
+	}
+
+	for _, tc := range testCases {
+		gotID, gotTitle := exampleIDTitle(&doc.Example{Name: tc.name, Suffix: tc.suffix})
+		if gotID != tc.wantID || gotTitle != tc.wantTitle {
+			t.Errorf("want {%q %q} but got {%q %q}", tc.wantID, tc.wantTitle, gotID, gotTitle)
+		}
+	}
+}
+
+func TestRenderAddedIn(t *testing.T) {
+	// testdata/goroot/src/sync/atomic is a subset of the std lib pkg,
+	// whose symbols are in testdata/goroot/api
+	versions, err := resolve.LoadAPIVersions("../testdata/goroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{"atomic.go"}
+	md, err := Render(context.Background(), "../testdata/goroot/src/sync/atomic", files, "sync/atomic", versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(md)
+	for _, want := range []string{
+		"<a id=\"Int64\"></a>\n## type Int64\n\n*Added in Go 1.19*\n",
+		"<a id=\"Int64.Load\"></a>\n### func (\\*Int64) Load\n\n*Added in Go 1.19*\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown should contain %q", want)
+		}
+	}
+	if strings.Count(got, "Added in") != 2 {
+		t.Error("markdown should only annotate symbols added after Go 1.0")
+	}
+}
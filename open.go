@@ -4,188 +4,112 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/http"
-	"path"
+	"os"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
+
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/server"
 )
 
 // cmdOpen is the primary functionality: it opens a browser for pkg in question.
 func cmdOpen(app *App) error {
 
-	if len(app.args) > 1 {
-		return fmt.Errorf("must supply maximum one arg to gohdoc, but received %d: [%s]",
+	if len(app.args) > 2 {
+		return fmt.Errorf("must supply maximum one arg (or a pkg and a symbol, as with go doc) to gohdoc, but received %d: [%s]",
 			len(app.args), strings.Join(app.args, " "))
 	}
 
-	err := requireServer(app)
-	if err != nil {
-		return err
+	arg := resolve.ParseArg(app.cwd, app.args)
+	if arg.Version != "" {
+		return cmdOpenVersion(app, arg)
 	}
-	err = loadServerPkgList(app)
+
+	err := loadPkgList(app)
 	if err != nil {
 		return err
 	}
-	// At this point, we know that the server is available, and app.serverPkgList is populated.
+	// At this point, app.pkgList is populated. The server is only
+	// required once there's a candidate pkg page to verify.
 	return doCmdOpen(app)
 }
 
 // doCmdOpen does the main work of cmdOpen.
 func doCmdOpen(app *App) error {
-	pth, pkg, fragment := processCmdOpenArgs(app)
-
-	// Try the path-based approach first.
-
-	// pth looks something like /go/src/github.com/neilotoole/gohdoc
-	// We'll iteratively look for a package that matches the path, trimming
-	// a front segment each time. That is, we'll search for:
-	//
-	//   go/src/github.com/neilotoole/gohdoc
-	//   src/github.com/neilotoole/gohdoc
-	//   github.com/neilotoole/gohdoc
-	//   neilotoole/gohdoc
-	//   gohdoc
-
-	// strip the leading slash, we don't need it
-	pth = strings.TrimPrefix(pth, "/")
-
-	parts := strings.Split(pth, "/")
-	for {
-		// reconstruct the path
-		findPkg := strings.Join(parts, "/")
-		log.Println("checking if pkg is listed:", findPkg)
-
-		for _, serverPkg := range app.serverPkgList {
-			if findPkg == serverPkg {
-				log.Println("found in pkg list, will attempt to verify page on server:", serverPkg)
-
-				ok, err := serverPkgPageOK(app, serverPkg, true)
-				if err != nil {
-					return err
-				}
-				if !ok {
-					return fmt.Errorf("should have been able to open pkg page %s but failed: %v",
-						serverPkg, err)
-				}
-
-				url := absPkgURL(app, serverPkg, fragment)
-				return openBrowser(app, url)
-			}
-		}
-
-		if len(parts) == 1 {
-			break
-		}
-
-		parts = parts[1:]
+	res, err := resolvePkg(app)
+	if err != nil {
+		return err
 	}
 
-	// We weren't able to match the path (or subsections of it) against
-	// serverPkgList, so we'll search for the pkg term.
-	// When we get this far, we could be searching for partial
-	// names like "byt", or "encoding/jso".
-	matches, exactMatch := getPkgMatches(app.serverPkgList, pkg)
-	if exactMatch {
-		// If we've got an exact match, we only want to open that page
-		ok, err := serverPkgPageOK(app, matches[0], false)
-		if err != nil {
-			return err
-		}
-		if !ok {
-			// shouldn't happen
-			return fmt.Errorf("should have been able to open this, but it seems not to exist: %s", matches[0])
-		}
-
-		return openBrowser(app, absPkgURL(app, matches[0], fragment))
+	err = openPkgPage(app, res.Pkg, res.Fragment)
+	if err == nil {
+		printAddedIn(app, res.Pkg, res.Fragment)
 	}
-
-	// We don't have an exact match, so we'll iterate over the set of
-	// possible matches and check if we can open that page.
-	for _, match := range matches {
-		ok, err := serverPkgPageOK(app, match, false)
-		if err != nil {
-			return err
-		}
-		if ok {
-			err = openBrowser(app, absPkgURL(app, match, fragment))
-			printPossibleMatches(app, pkg, matches)
-			return err
-		}
+	if len(res.PossibleMatches) > 0 {
+		printPossibleMatches(app, res.Term, res.PossibleMatches)
 	}
-
-	return fmt.Errorf("failed to find in server pkg list: %s", pkg)
+	return err
 }
 
-// processCmdOpenArgs processes the command line args.
-// This function returns a suggested absolute path, package name,
-// and fragment (which may be empty). If the arg is relative, a
-// suggested absolute path is constructed by joining with app.cwd.
-// The returned path will always use forward slash (thus on Windows, the
-// returned path is not a valid path).
-func processCmdOpenArgs(app *App) (pkgpath, pkg, fragment string) {
-	// There are several possibilities for args passed to the program, such as:
-	// - no args                     = gohdoc .
-	// - gohdoc .                    = gohdoc CWD
-	// - gohdoc some/relative/path   = transformed to absolute path
-	// - gohdoc arbitrary/pkg        = try relative path first, then search for arbitrary/pkg
-	// - gohdoc /some/absolute/path  = passed through after path.Clean()
-	//
-	// - gohdoc fmt#Println          = open fmt with #fragment
-	// - gohdoc fmt/#Println         = same as above
-	// - gohdoc #Func                = open current dir godoc with #fragment
-	// - gohdoc ./#Func              = same as above
-	// - gohdoc .#Func               = same as above
-
-	cwd := cleanFilePath(app.cwd)
-	cwdBase := path.Base(cwd)
-
-	if len(app.args) == 0 || strings.TrimSpace(app.args[0]) == "" {
-		return cwd, cwdBase, ""
-	}
-
-	arg := strings.TrimSpace(app.args[0])
-	arg = cleanFilePath(arg)
-
-	if i := strings.IndexRune(arg, '#'); i >= 0 {
-		if len(arg) == 1 {
-			// i.e. arg is "#"
-			return cwd, cwdBase, ""
+// resolvePkg determines which pkg the cmd line arg refers to, verifying
+// that the pkg page is available on the server (starting the server if
+// necessary). It is required that app.pkgList is already loaded.
+// With -explain, the resolution trace is printed.
+func resolvePkg(app *App) (res *resolve.Result, err error) {
+	if app.flagExplain {
+		app.srv.OnProbe = func(p server.Probe) {
+			app.probes = append(app.probes, p)
 		}
-
-		if i < len(arg)-2 {
-			frag := arg[i+1:]
-			fragment = frag
+		defer func() { printExplanation(app, os.Stdout, res, err) }()
+	}
+
+	// The server is required (once) only when there's a candidate pkg
+	// page to verify. The pages may be verified concurrently.
+	var once sync.Once
+	var requireErr error
+	arg := resolve.ParseArg(app.cwd, app.args)
+	pageOK := func(ctx context.Context, pkg string, retry bool) (bool, error) {
+		once.Do(func() { requireErr = requireServer(app) })
+		if requireErr != nil {
+			return false, requireErr
 		}
-		arg = arg[0:i]
+		return app.srv.PkgPageOK(ctx, pkg, retry)
 	}
-
-	arg = path.Clean(arg)
-	if arg == "." {
-		return cwd, cwdBase, fragment
+	res, app.trace, err = resolve.Explain(app.ctx, arg, app.pkgList, pageOK)
+	if err != nil {
+		return nil, err
 	}
 
-	if path.IsAbs(arg) {
-		return arg, path.Base(arg), fragment
+	if res.Symbol != "" {
+		step := &resolve.Step{Strategy: "symbol"}
+		app.trace.Steps = append(app.trace.Steps, step)
+		res.Fragment, err = symbolFragment(app, res.Pkg, res.Symbol)
+		if err != nil {
+			step.Note = err.Error()
+			return nil, err
+		}
+		step.Note = fmt.Sprintf("symbol %s matches id %s of the %s pkg page", res.Symbol, res.Fragment, res.Pkg)
 	}
-
-	return path.Join(cwd, arg), arg, fragment
+	return res, nil
 }
 
-// cleanFilePath strips any Windows volume name and converts
-// to forward slash. This isn't particularly robust, but being
-// that we don't need an actual working path, it should suffice.
-// Also, too lazy to set project up to use platform-specific tests.
-func cleanFilePath(p string) string {
-	p = path.Clean(p)
-	i := strings.IndexRune(p, ':')
-	if i >= 1 {
-		p = p[i+1:]
+// symbolFragment returns the fragment (i.e. the element id) of the
+// server page for pkg that go doc-style symbol sym refers to, e.g.
+// "Client.Do" for "client.do". As with go doc's -u flag, unexported
+// symbols are matched only in -all mode.
+func symbolFragment(app *App, pkg, sym string) (string, error) {
+	ids, err := app.srv.PkgPageIDs(app.ctx, pkg)
+	if err != nil {
+		return "", err
 	}
 
-	p = strings.Replace(p, `\`, "/", -1)
-	p = path.Clean(p)
-	return p
+	fragment, ok := resolve.MatchSymbol(ids, sym, app.srv.Mode.All)
+	if !ok {
+		return "", fmt.Errorf("symbol %q not found in pkg %s", sym, pkg)
+	}
+	log.Printf("matched symbol %q to %s#%s", sym, pkg, fragment)
+	return fragment, nil
 }
 
 func printPossibleMatches(app *App, arg string, matches []string) {
@@ -201,65 +125,45 @@ func printPossibleMatches(app *App, arg string, matches []string) {
 	printPkgsWithLink(app, matches)
 }
 
-// serverPkgPageOK returns true, nil if pkgPath exists on the server.
-// The pkgPath arg should be a well-formed pkg path, e.g. "bytes", "encoding/json",
-// or "github.com/neilotoole/gohdoc".
-// An error is returned if a http failure occurs.
-func serverPkgPageOK(app *App, pkgPath string, retry bool) (ok bool, err error) {
-	if strings.HasPrefix(pkgPath, "/") || strings.HasSuffix(pkgPath, "/") {
-		return false, fmt.Errorf("invalid pkg path (has '/' prefix or suffix): %s", pkgPath)
-	}
-
-	pageURL := absPkgURL(app, pkgPath, "")
-
-	ctx := app.ctx
-	if retry {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Millisecond*500)
-		defer cancel()
-	}
-
-	doneCh := ctx.Done()
-
-	var resp *http.Response
-	i := 1
-	for {
-		log.Printf("verifying pkg page (attempt %d): %s\n", i, pageURL)
-		select {
-		case <-doneCh:
-			return false, ctx.Err()
-		default:
-		}
-
-		resp, err = http.Head(pageURL)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			return true, nil
+// openPkgPage opens a browser for the server page of pkg. If -all mode
+// is active and fragment is non-empty, the page is first checked to
+// contain fragment, because the main reason to use -all is to target
+// an unexported symbol, and godoc silently ignores an unknown fragment.
+func openPkgPage(app *App, pkg, fragment string) error {
+	if app.srv.Mode.All && !app.srv.Mode.Src && fragment != "" {
+		err := verifyPkgPageFragment(app, pkg, fragment)
+		if err != nil {
+			return err
 		}
-
-		time.Sleep(time.Millisecond * 100)
-		i++
 	}
 
+	return openBrowser(app, app.srv.PkgURL(pkg, fragment))
+}
+
+// verifyPkgPageFragment returns an error if the server page for pkg
+// does not have an element with id fragment.
+func verifyPkgPageFragment(app *App, pkg, fragment string) error {
+	ok, err := app.srv.PkgPageHasID(app.ctx, pkg, fragment)
 	if err != nil {
-		return false, fmt.Errorf("failed to access godoc http server: %v", err)
+		return err
 	}
-
-	return false, nil
+	if !ok {
+		return fmt.Errorf("symbol %q not found in pkg %s", fragment, pkg)
+	}
+	return nil
 }
 
-// openBrowser opens a browser for url. It delegates creation of the platform-specific
-// exec.Cmd to build tag-gated implementations of openBrowserCmd.
+// openBrowser opens a browser for url.
 func openBrowser(app *App, url string) error {
 	log.Println("attempting to open a browser for:", url)
 
-	cmd := openBrowserCmd(app.ctx, url) // openBrowserCmd is platform-specific
-	err := cmd.Run()
+	err := app.browser.Open(app.ctx, url)
 	if err != nil {
 		log.Printf("failed to open browser for %s: %v", url, err)
 		return err
 	}
 
-	if app.cmd != nil {
+	if app.srv.Proc != nil {
 		// if non-nil, we did start a server
 		log.Printf("Opening %s on newly-started server\n", url)
 	} else {
@@ -270,29 +174,29 @@ func openBrowser(app *App, url string) error {
 	return nil
 }
 
-// absPkgURL returns the godoc http server URL for the supplied pkg.
-func absPkgURL(app *App, fullPkgPath string, fragment string) string {
-
-	fullPkgPath = strings.TrimPrefix(fullPkgPath, "/")
-	fragment = strings.TrimSuffix(fragment, "#")
-	if len(fragment) == 0 {
-		return fmt.Sprintf("http://localhost:%d/pkg/%s/", app.port, fullPkgPath)
-	}
-
-	return fmt.Sprintf("http://localhost:%d/pkg/%s/#%s", app.port, fullPkgPath, fragment)
-}
-
 // printPkgsWithLink will - for each pkg - print a line with the pkg name and link.
+// A std lib pkg added after Go 1.0 is annotated with its Go version.
 func printPkgsWithLink(app *App, pkgs []string) {
-	var width int
-	for _, m := range pkgs {
-		if len(m) > width {
-			width = len(m)
+	var width, urlWidth int
+	urls := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		urls[i] = app.srv.PkgURL(pkg, "")
+		if len(pkg) > width {
+			width = len(pkg)
+		}
+		if len(urls[i]) > urlWidth {
+			urlWidth = len(urls[i])
 		}
 	}
 	tpl := "%-" + strconv.Itoa(width) + "s    %s\n"
+	tplAdded := "%-" + strconv.Itoa(width) + "s    %-" + strconv.Itoa(urlWidth) + "s    added in %s\n"
 
-	for _, pkg := range pkgs {
-		fmt.Printf(tpl, pkg, absPkgURL(app, pkg, ""))
+	versions := loadAPIVersions(app)
+	for i, pkg := range pkgs {
+		if v := versions.AddedIn(pkg, ""); v != "" {
+			fmt.Printf(tplAdded, pkg, urls[i], v)
+			continue
+		}
+		fmt.Printf(tpl, pkg, urls[i])
 	}
 }
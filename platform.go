@@ -0,0 +1,39 @@
+package main
+
+import (
+	"go/build"
+	"runtime"
+	"strings"
+)
+
+// buildContext returns the build context used to load local pkgs, for
+// the platform selected by -goos, -goarch and -tags, and the toolchain
+// selected by -go or -goroot.
+func buildContext(app *App) *build.Context {
+	bctx := build.Default
+	if app.srv.GOROOT != "" {
+		bctx.GOROOT = app.srv.GOROOT
+	}
+
+	p := app.srv.Platform
+	if p.GOOS != "" {
+		bctx.GOOS = p.GOOS
+	}
+	if p.GOARCH != "" {
+		bctx.GOARCH = p.GOARCH
+	}
+	if bctx.GOOS != runtime.GOOS || bctx.GOARCH != runtime.GOARCH {
+		// As with the go command, cgo is disabled when cross-compiling
+		bctx.CgoEnabled = false
+	}
+	bctx.BuildTags = append(append([]string(nil), bctx.BuildTags...), p.Tags...)
+	return &bctx
+}
+
+// parseTags parses the value of the -tags flag. As with the go command,
+// the tags are comma-separated, although space-separated is also accepted.
+func parseTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neilotoole/gohdoc/server"
+)
+
+func TestParseTags(t *testing.T) {
+	testCases := map[string][]string{
+		"":            {},
+		"integration": {"integration"},
+		"a,b":         {"a", "b"},
+		" a, b  c ":   {"a", "b", "c"},
+	}
+	for s, want := range testCases {
+		if got := parseTags(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: want %v but got %v", s, want, got)
+		}
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	app := &App{srv: &server.Server{Platform: server.Platform{GOOS: "plan9", GOARCH: "arm", Tags: []string{"foo"}}}}
+
+	bctx := buildContext(app)
+	if bctx.GOOS != "plan9" || bctx.GOARCH != "arm" || bctx.CgoEnabled {
+		t.Errorf("want plan9/arm without cgo but got %s/%s, cgo %v", bctx.GOOS, bctx.GOARCH, bctx.CgoEnabled)
+	}
+
+	// The pkg's files are those of the platform
+	bp, err := bctx.Import("os", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range bp.GoFiles {
+		if f == "file_plan9.go" {
+			found = true
+		}
+		if f == "file_unix.go" {
+			t.Errorf("want no unix files but got %s", f)
+		}
+	}
+	if !found {
+		t.Errorf("want file_plan9.go but got %v", bp.GoFiles)
+	}
+
+	ok, err := bctx.MatchFile("testdata/platform", "foo.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("want file with build tag foo to match")
+	}
+}
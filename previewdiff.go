@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/apidiff"
+)
+
+// cmdPreviewDiff shows how the working tree changes the rendered doc of
+// the pkg arg (default the current pkg), compared to git ref (default
+// HEAD). The args are [pkg] [ref]: a single arg is the ref if it names a
+// git commit, and otherwise the pkg. The pkg at ref is checked out into
+// a temporary git worktree, and a HTML page highlighting added, removed
+// and reworded doc text is served and opened in the browser.
+func cmdPreviewDiff(app *App) error {
+	if len(app.args) > 2 {
+		return fmt.Errorf("preview-diff command takes maximum two args (a pkg and a git ref), but received %d: [%s]",
+			len(app.args), strings.Join(app.args, " "))
+	}
+
+	ref := "HEAD"
+	switch {
+	case len(app.args) == 2:
+		ref = app.args[1]
+		app.args = app.args[:1]
+	case len(app.args) == 1 && apidiff.IsGitRef(app.ctx, app.cwd, app.args[0]):
+		ref = app.args[0]
+		app.args = nil
+	}
+
+	bp, _, err := importLocalPkg(app)
+	if err != nil {
+		return err
+	}
+
+	src, err := gitRefAPISource(app, bp.Dir, ref)
+	if err != nil {
+		return err
+	}
+	defer src.cleanup()
+
+	oldAPI, err := apidiff.Load(app.ctx, buildContext(app), src.dir)
+	if err != nil && apidiff.IsNoPkg(src.dir, err) {
+		// The pkg is new since ref, so all of its docs are added
+		log.Printf("no pkg %s at %s: %v", bp.ImportPath, ref, err)
+		oldAPI, err = map[string]apidiff.Sym{}, nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", ref, err)
+	}
+	newAPI, err := apidiff.Load(app.ctx, buildContext(app), bp.Dir)
+	if err != nil {
+		return fmt.Errorf("working tree: %v", err)
+	}
+
+	changes := apidiff.DiffDocs(oldAPI, newAPI)
+	if len(changes) == 0 {
+		fmt.Printf("No doc changes to %s between %s and working tree\n", bp.ImportPath, ref)
+		return nil
+	}
+
+	for _, c := range changes {
+		fmt.Printf("%-8s  %s\n", c.Kind, c.ID)
+	}
+
+	page, err := apidiff.DocChangesHTML(ref, changes)
+	if err != nil {
+		return err
+	}
+	return serveAndOpen(app, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	}))
+}
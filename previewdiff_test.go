@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/gohdoc/browser"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+func TestPreviewDiffPkg(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir, err := ioutil.TempDir("", "gohdoc_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, text string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	for _, sub := range []string{"sub", "docs", "added"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/preview\n")
+	write("top.go", "// Package preview is the top pkg.\npackage preview\n\n// Top is unchanged.\nfunc Top() {}\n")
+	write("sub/sub.go", "// Package sub is the sub pkg.\npackage sub\n\n// Sub is the old doc.\nfunc Sub() {}\n")
+	git("init", "-q")
+	git("add", "-A")
+	write("docs/README.md", "Not a pkg at HEAD.\n")
+	git("commit", "-q", "-m", "initial")
+	write("sub/sub.go", "// Package sub is the sub pkg.\npackage sub\n\n// Sub is the new doc.\nfunc Sub() {}\n")
+	// Pkgs that don't exist at HEAD: all their docs are added
+	write("docs/docs.go", "// Package docs is new.\npackage docs\n")
+	write("added/added.go", "// Package added is new.\npackage added\n")
+
+	testCases := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "No doc changes to example.com/preview between HEAD and working tree"},
+		{args: []string{"HEAD"}, want: "No doc changes to example.com/preview between HEAD and working tree"},
+		{args: []string{"./sub"}, want: "reworded  Sub"},
+		{args: []string{"./sub", "HEAD"}, want: "reworded  Sub"},
+		{args: []string{"./docs"}, want: "added     pkg-overview"},
+		{args: []string{"./added", "HEAD"}, want: "added     pkg-overview"},
+	}
+
+	for _, tc := range testCases {
+		ctx, cancel := context.WithCancel(context.Background())
+		var opened []string
+		app := &App{
+			srv:  &server.Server{},
+			cwd:  dir,
+			ctx:  ctx,
+			args: tc.args,
+			browser: browser.OpenerFunc(func(ctx context.Context, url string) error {
+				opened = append(opened, url)
+				cancel()
+				return nil
+			}),
+		}
+
+		var err error
+		out := captureStdout(t, func() { err = cmdPreviewDiff(app) })
+		cancel()
+		if err != nil {
+			t.Errorf("%v: %v", tc.args, err)
+			continue
+		}
+		if got := strings.SplitN(out, "\n", 2)[0]; got != tc.want {
+			t.Errorf("%v: want %q but got %q", tc.args, tc.want, got)
+		}
+		if wantOpened := !strings.HasPrefix(tc.want, "No doc changes"); wantOpened != (len(opened) == 1) {
+			t.Errorf("%v: want page opened %v but got %v", tc.args, wantOpened, opened)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package resolve
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// APIVersions holds the Go version in which each std lib pkg and
+// exported identifier was added, e.g. "go1.21" for slices#Clone,
+// as recorded in a toolchain's GOROOT/api/go1*.txt files.
+type APIVersions struct {
+	// versions is keyed by pkg (e.g. "slices") or by pkg and godoc
+	// fragment (e.g. "bytes#Buffer.AvailableBuffer").
+	versions map[string]string
+}
+
+// LoadAPIVersions parses the api files (go1.txt, go1.1.txt, etc) of the
+// toolchain in goroot. An identifier's version is that of the first file
+// that lists it, and a pkg's version is that of its first identifier.
+func LoadAPIVersions(goroot string) (*APIVersions, error) {
+	files, err := filepath.Glob(filepath.Join(goroot, "api", "go1*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no api files found in GOROOT %s", goroot)
+	}
+
+	// Process the files in version order, i.e. go1.2.txt before go1.10.txt
+	minor := func(file string) int {
+		v := strings.TrimSuffix(filepath.Base(file), ".txt")
+		n, _ := strconv.Atoi(strings.TrimPrefix(v, "go1."))
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return minor(files[i]) < minor(files[j])
+	})
+
+	a := &APIVersions{versions: map[string]string{}}
+	for _, file := range files {
+		err = a.load(file, strings.TrimSuffix(filepath.Base(file), ".txt"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// load records version for each pkg and identifier in api file
+// that isn't already recorded.
+func (a *APIVersions) load(file, version string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		pkg, id, ok := parseAPILine(sc.Text())
+		if !ok {
+			continue
+		}
+		for _, key := range []string{pkg, pkg + "#" + id} {
+			if _, ok := a.versions[key]; !ok {
+				a.versions[key] = version
+			}
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return fmt.Errorf("failed to read api file %s: %v", file, err)
+	}
+	return nil
+}
+
+// Version returns the Go version (e.g. "go1.21") in which the std lib
+// identifier with godoc fragment id (e.g. "Buffer.Len") was added to
+// pkg. If id is empty, the version in which pkg was added is returned.
+// It returns empty string if the version is not known, e.g. because
+// pkg is not in the std lib.
+func (a *APIVersions) Version(pkg, id string) string {
+	if a == nil {
+		return ""
+	}
+	if id == "" {
+		return a.versions[pkg]
+	}
+	return a.versions[pkg+"#"+id]
+}
+
+// AddedIn returns the Go version, e.g. "Go 1.21", in which std lib pkg
+// was added or, if id is non-empty, in which pkg's identifier with godoc
+// fragment id was added. As per pkg.go.dev, there's no annotation for
+// Go 1.0, so empty string is returned for Go 1.0 or an unknown version.
+func (a *APIVersions) AddedIn(pkg, id string) string {
+	v := a.Version(pkg, id)
+	if v == "" || !NewerGoVersion(v, "go1") {
+		return ""
+	}
+	return "Go " + strings.TrimPrefix(v, "go")
+}
+
+// NewerGoVersion returns true if Go version v (e.g. "go1.21") is newer
+// than version than (e.g. "1.20", "go1.20" or "1.20.3").
+func NewerGoVersion(v, than string) bool {
+	return compareVersions(goSemver(v), goSemver(than)) > 0
+}
+
+// parseAPILine returns the pkg and godoc fragment of the identifier
+// declared by api file line, e.g. "bytes" and "Buffer.Len" for:
+//
+//	pkg bytes, method (*Buffer) Len() int
+//
+// It returns false if line doesn't declare an exported identifier.
+func parseAPILine(line string) (pkg, id string, ok bool) {
+	if !strings.HasPrefix(line, "pkg ") {
+		return "", "", false
+	}
+	i := strings.Index(line, ", ")
+	if i < 0 {
+		return "", "", false
+	}
+
+	// e.g. "pkg syscall (windows-386), const AF_INET = 2"
+	pkg, decl := line[len("pkg "):i], line[i+2:]
+	if j := strings.Index(pkg, " ("); j >= 0 {
+		pkg = pkg[:j]
+	}
+
+	kind, decl := cutSpace(decl)
+	switch kind {
+	case "const", "var", "func":
+		id = leadingIdent(decl)
+	case "method":
+		// e.g. "(*List[$0]) Back() *Element[$0]"
+		end := strings.Index(decl, ") ")
+		if !strings.HasPrefix(decl, "(") || end < 0 {
+			return "", "", false
+		}
+		recv := leadingIdent(strings.TrimPrefix(decl[1:end], "*"))
+		id = recv + "." + leadingIdent(decl[end+2:])
+	case "type":
+		// e.g. "Context struct", "Context struct, Dir string", or
+		// "Seq2[$0 interface{}, $1 interface{}] func(...)"
+		id = leadingIdent(decl)
+		rest := skipBrackets(decl[len(id):])
+		if strings.HasPrefix(rest, " struct, ") || strings.HasPrefix(rest, " interface, ") {
+			member := rest[strings.Index(rest, ", ")+2:]
+			if strings.HasPrefix(member, "embedded ") {
+				// godoc doesn't have fragments for embedded fields
+				return "", "", false
+			}
+			name := leadingIdent(member)
+			if !isExported(name) {
+				return "", "", false
+			}
+			id += "." + name
+		}
+	default:
+		return "", "", false
+	}
+
+	if !isExported(id) {
+		return "", "", false
+	}
+	return pkg, id, true
+}
+
+// cutSpace splits s around its first space.
+func cutSpace(s string) (before, after string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// leadingIdent returns the identifier at the start of s.
+func leadingIdent(s string) string {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && !unicode.IsDigit(r) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// skipBrackets returns s with any leading bracketed text (e.g. type
+// params) removed.
+func skipBrackets(s string) string {
+	if !strings.HasPrefix(s, "[") {
+		return s
+	}
+
+	var depth int
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return s[i+1:]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package resolve
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseAPILine(t *testing.T) {
+	testCases := []struct {
+		line string
+		pkg  string
+		id   string
+	}{
+		{line: "pkg fmt, func Println(...interface{}) (int, error)", pkg: "fmt", id: "Println"},
+		{line: "pkg bytes, method (*Buffer) AvailableBuffer() []uint8 #53685", pkg: "bytes", id: "Buffer.AvailableBuffer"},
+		{line: "pkg crypto/tls, method (ConnectionState) ExportKeyingMaterial(string, []uint8, int) ([]uint8, error)", pkg: "crypto/tls", id: "ConnectionState.ExportKeyingMaterial"},
+		{line: "pkg container/list, method (*List[$0]) Back() *Element[$0]", pkg: "container/list", id: "List.Back"},
+		{line: "pkg net/http, type Client struct", pkg: "net/http", id: "Client"},
+		{line: "pkg go/build, type Context struct, Dir string", pkg: "go/build", id: "Context.Dir"},
+		{line: "pkg io, type Reader interface, Read([]uint8) (int, error)", pkg: "io", id: "Reader.Read"},
+		{line: "pkg iter, type Seq2[$0 interface{}, $1 interface{}] func(func($0, $1) bool) #61897", pkg: "iter", id: "Seq2"},
+		{line: "pkg database/sql, type Null[$0 interface{}] struct, Valid bool #60370", pkg: "database/sql", id: "Null.Valid"},
+		{line: "pkg os (windows-386), const DevNull = \"NUL\"", pkg: "os", id: "DevNull"},
+		{line: "pkg unicode, var Adlam *RangeTable", pkg: "unicode", id: "Adlam"},
+		{line: "pkg runtime, type BlockProfileRecord struct, embedded StackRecord"},
+		{line: "pkg io, type Reader interface, unexported methods"},
+		{line: "# CL 134210043 archive/zip: add Writer.Flush"},
+		{line: ""},
+	}
+
+	for _, tc := range testCases {
+		pkg, id, ok := parseAPILine(tc.line)
+		if ok != (tc.pkg != "") || pkg != tc.pkg || id != tc.id {
+			t.Errorf("%q: want %q %q but got %q %q (%v)", tc.line, tc.pkg, tc.id, pkg, id, ok)
+		}
+	}
+}
+
+func TestLoadAPIVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"api/go1.txt":    "pkg bytes, type Buffer struct\npkg bytes, method (*Buffer) Len() int\n",
+		"api/go1.2.txt":  "pkg bytes, method (*Buffer) Grow(int)\n",
+		"api/go1.10.txt": "pkg strings, type Builder struct\npkg bytes, method (*Buffer) Grow(int)\n",
+		"api/except.txt": "pkg bytes, method (*Buffer) Len() int\n",
+	})
+
+	a, err := LoadAPIVersions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		pkg  string
+		id   string
+		want string
+	}{
+		{pkg: "bytes", id: "Buffer", want: "go1"},
+		{pkg: "bytes", id: "Buffer.Len", want: "go1"},
+		{pkg: "bytes", id: "Buffer.Grow", want: "go1.2"},
+		{pkg: "strings", id: "Builder", want: "go1.10"},
+		{pkg: "strings", want: "go1.10"},
+		{pkg: "bytes", want: "go1"},
+		{pkg: "bytes", id: "Buffer.Nope"},
+		{pkg: "github.com/my/pkg"},
+	}
+	for _, tc := range testCases {
+		if got := a.Version(tc.pkg, tc.id); got != tc.want {
+			t.Errorf("%s#%s: want %q but got %q", tc.pkg, tc.id, tc.want, got)
+		}
+	}
+
+	if !NewerGoVersion("go1.21", "1.20") || NewerGoVersion("go1.20", "go1.20.3") || NewerGoVersion("go1", "1.0") {
+		t.Error("NewerGoVersion: unexpected result")
+	}
+}
@@ -0,0 +1,123 @@
+// Package resolve determines which pkg (and symbol) a gohdoc cmd line
+// arg refers to.
+package resolve
+
+import (
+	"path"
+	"strings"
+)
+
+// Arg is a processed gohdoc cmd line arg.
+type Arg struct {
+	// Path is a suggested absolute path for the arg. If the arg is
+	// relative, the path is constructed by joining with the cwd. The
+	// path always uses forward slash (thus on Windows, the path is not
+	// a valid path).
+	Path string
+
+	// Pkg is the pkg name or search term, e.g. "gohdoc" or "encoding/jso".
+	Pkg string
+
+	// Fragment is the (possibly empty) fragment, e.g. "Println".
+	Fragment string
+
+	// Symbol is the (possibly empty) go doc-style symbol, e.g. "Unmarshal"
+	// or "client.do" for args "json Unmarshal" or "http client.do". Unlike
+	// Fragment, which is used verbatim, Symbol is matched against the pkg's
+	// identifiers as go doc does (see MatchSymbol).
+	Symbol string
+
+	// Version is the (possibly empty) module version, e.g. "v1.4.0" for
+	// arg "github.com/foo/bar@v1.4.0". It may also be a module query,
+	// such as "latest".
+	Version string
+}
+
+// ParseArg processes the command line args. If args is empty, the arg
+// is taken to be the cwd. As with "go doc json Unmarshal", a second
+// element of args is taken to be a symbol of the pkg.
+func ParseArg(cwd string, args []string) Arg {
+	arg := parseArg(cwd, args)
+	if len(args) > 1 && arg.Fragment == "" {
+		arg.Symbol = strings.TrimSpace(args[1])
+	}
+	return arg
+}
+
+// parseArg processes the first element of args.
+func parseArg(cwd string, args []string) Arg {
+	// There are several possibilities for args passed to the program, such as:
+	// - no args                     = gohdoc .
+	// - gohdoc .                    = gohdoc CWD
+	// - gohdoc some/relative/path   = transformed to absolute path
+	// - gohdoc arbitrary/pkg        = try relative path first, then search for arbitrary/pkg
+	// - gohdoc /some/absolute/path  = passed through after path.Clean()
+	//
+	// - gohdoc fmt#Println          = open fmt with #fragment
+	// - gohdoc fmt/#Println         = same as above
+	// - gohdoc #Func                = open current dir godoc with #fragment
+	// - gohdoc ./#Func              = same as above
+	// - gohdoc .#Func               = same as above
+	//
+	// - gohdoc pkg@v1.4.0#Func      = open pkg at module version v1.4.0 with #fragment
+	//
+	// Args in go doc form, e.g. "json.Unmarshal", are treated as a pkg
+	// here; Resolve considers the go doc interpretations.
+
+	cwd = CleanFilePath(cwd)
+	cwdBase := path.Base(cwd)
+
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return Arg{Path: cwd, Pkg: cwdBase}
+	}
+
+	arg := strings.TrimSpace(args[0])
+	arg = CleanFilePath(arg)
+
+	var fragment string
+	if i := strings.IndexRune(arg, '#'); i >= 0 {
+		if len(arg) == 1 {
+			// i.e. arg is "#"
+			return Arg{Path: cwd, Pkg: cwdBase}
+		}
+
+		if i < len(arg)-2 {
+			frag := arg[i+1:]
+			fragment = frag
+		}
+		arg = arg[0:i]
+	}
+
+	arg = path.Clean(arg)
+
+	var version string
+	if i := strings.LastIndex(arg, "@"); i >= 0 && !strings.Contains(arg[i:], "/") {
+		arg, version = path.Clean(arg[:i]), arg[i+1:]
+	}
+
+	if arg == "." {
+		return Arg{Path: cwd, Pkg: cwdBase, Fragment: fragment, Version: version}
+	}
+
+	if path.IsAbs(arg) {
+		return Arg{Path: arg, Pkg: path.Base(arg), Fragment: fragment, Version: version}
+	}
+
+	return Arg{Path: path.Join(cwd, arg), Pkg: arg, Fragment: fragment, Version: version}
+}
+
+// CleanFilePath strips any Windows volume name and converts
+// to forward slash. This isn't particularly robust, but being
+// that we don't need an actual working path, it should suffice.
+// Also, too lazy to set project up to use platform-specific tests.
+func CleanFilePath(p string) string {
+	p = path.Clean(p)
+	i := strings.IndexRune(p, ':')
+	if i >= 1 {
+		p = p[i+1:]
+	}
+
+	p = strings.Replace(p, `\`, "/", -1)
+	p = path.Clean(p)
+	return p
+}
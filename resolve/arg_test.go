@@ -1,4 +1,4 @@
-package main
+package resolve
 
 import (
 	"fmt"
@@ -23,7 +23,7 @@ func TestCleanFilePath(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.path, func(t *testing.T) {
-			got := cleanFilePath(tc.path)
+			got := CleanFilePath(tc.path)
 			if got != tc.want {
 				t.Errorf("want %q but got %q", tc.want, got)
 			}
@@ -31,7 +31,7 @@ func TestCleanFilePath(t *testing.T) {
 	}
 }
 
-func TestProcessCmdOpenArgs(t *testing.T) {
+func TestParseArg(t *testing.T) {
 	const (
 		cwd    = "/go/src/github.com/neilotoole/gohdoc"
 		cwdWin = `C:\go\src\github.com\neilotoole\gohdoc`
@@ -43,6 +43,7 @@ func TestProcessCmdOpenArgs(t *testing.T) {
 		wantPath string
 		wantPkg  string
 		wantFrag string
+		wantVer  string
 	}{
 		{arg0: "", wantPath: cwd, wantPkg: "gohdoc"},
 		{arg0: "#Frag", wantPath: cwd, wantPkg: "gohdoc", wantFrag: "Frag"},
@@ -70,6 +71,9 @@ func TestProcessCmdOpenArgs(t *testing.T) {
 		{arg0: "sub/pkg/.#Frag", wantPath: "/go/src/github.com/neilotoole/gohdoc/sub/pkg", wantPkg: "sub/pkg", wantFrag: "Frag"},
 		{arg0: "fmt", wantPath: "/go/src/github.com/neilotoole/gohdoc/fmt", wantPkg: "fmt"},
 		{arg0: "fmt#Println", wantPath: "/go/src/github.com/neilotoole/gohdoc/fmt", wantPkg: "fmt", wantFrag: "Println"},
+		{arg0: "github.com/foo/bar@v1.4.0", wantPath: "/go/src/github.com/neilotoole/gohdoc/github.com/foo/bar", wantPkg: "github.com/foo/bar", wantVer: "v1.4.0"},
+		{arg0: "github.com/foo/bar/sub@latest#Client", wantPath: "/go/src/github.com/neilotoole/gohdoc/github.com/foo/bar/sub", wantPkg: "github.com/foo/bar/sub", wantFrag: "Client", wantVer: "latest"},
+		{arg0: "github.com/foo/bar@v1.4.0/#Client.Do", wantPath: "/go/src/github.com/neilotoole/gohdoc/github.com/foo/bar", wantPkg: "github.com/foo/bar", wantFrag: "Client.Do", wantVer: "v1.4.0"},
 		{arg0: `C:\go\src\github.com/neilotoole/gohdoc`, windows: true, wantPath: "/go/src/github.com/neilotoole/gohdoc", wantPkg: "gohdoc"},
 		{arg0: `\\server\go\src\github.com/neilotoole/gohdoc`, windows: true, wantPath: "/server/go/src/github.com/neilotoole/gohdoc", wantPkg: "gohdoc"},
 	}
@@ -81,14 +85,39 @@ func TestProcessCmdOpenArgs(t *testing.T) {
 			if tc.windows {
 				cwd = cwdWin
 			}
-			app := &App{cwd: cwd, args: []string{tc.arg0}}
+			arg := ParseArg(cwd, []string{tc.arg0})
+			gotPath, gotPkg, gotFrag, gotVer := arg.Path, arg.Pkg, arg.Fragment, arg.Version
 
-			gotPath, gotPkg, gotFrag := processCmdOpenArgs(app)
-
-			if gotPath != tc.wantPath || gotPkg != tc.wantPkg || gotFrag != tc.wantFrag {
-				t.Errorf("Wanted {%q %q %q} but got {%q %q %q}",
-					tc.wantPath, tc.wantPkg, tc.wantFrag, gotPath, gotPkg, gotFrag)
+			if gotPath != tc.wantPath || gotPkg != tc.wantPkg || gotFrag != tc.wantFrag || gotVer != tc.wantVer {
+				t.Errorf("Wanted {%q %q %q %q} but got {%q %q %q %q}",
+					tc.wantPath, tc.wantPkg, tc.wantFrag, tc.wantVer, gotPath, gotPkg, gotFrag, gotVer)
 			}
 		})
 	}
 }
+
+func TestParseArgSymbol(t *testing.T) {
+	const cwd = "/go/src/github.com/neilotoole/gohdoc"
+
+	testCases := []struct {
+		args     []string
+		wantPkg  string
+		wantFrag string
+		wantSym  string
+	}{
+		{args: []string{"json"}, wantPkg: "json"},
+		{args: []string{"json", "Unmarshal"}, wantPkg: "json", wantSym: "Unmarshal"},
+		{args: []string{"net/http", " client.do "}, wantPkg: "net/http", wantSym: "client.do"},
+		{args: []string{".", "App"}, wantPkg: "gohdoc", wantSym: "App"},
+		// The fragment takes precedence
+		{args: []string{"json#Marshal", "Unmarshal"}, wantPkg: "json", wantFrag: "Marshal"},
+	}
+
+	for _, tc := range testCases {
+		arg := ParseArg(cwd, tc.args)
+		if arg.Pkg != tc.wantPkg || arg.Fragment != tc.wantFrag || arg.Symbol != tc.wantSym {
+			t.Errorf("%v: want {%q %q %q} but got {%q %q %q}", tc.args,
+				tc.wantPkg, tc.wantFrag, tc.wantSym, arg.Pkg, arg.Fragment, arg.Symbol)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// GoFiles returns the names of the non-test Go files of bp.
+func GoFiles(bp *build.Package) []string {
+	var files []string
+	files = append(files, bp.GoFiles...)
+	files = append(files, bp.CgoFiles...)
+	return files
+}
+
+// FindDecl returns the name of the file (one of files, relative to dir)
+// and the line at which symbol is declared. The symbol arg takes
+// the same form as a godoc fragment, e.g. "Println" or "Buffer.Len".
+func FindDecl(ctx context.Context, dir string, files []string, symbol string) (filename string, line int, err error) {
+	recv, name := "", symbol
+	if i := strings.IndexRune(symbol, '.'); i >= 0 {
+		recv, name = symbol[:i], symbol[i+1:]
+	}
+
+	fset := token.NewFileSet()
+	for _, f := range files {
+		if err = ctx.Err(); err != nil {
+			return "", 0, err
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, f), nil, 0)
+		if err != nil {
+			return "", 0, err
+		}
+
+		if pos := findDeclPos(file, recv, name); pos.IsValid() {
+			log.Printf("found declaration of %s in %s", symbol, fset.Position(pos))
+			return f, fset.Position(pos).Line, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("declaration of %s not found", symbol)
+}
+
+// findDeclPos returns the position of the declaration of name (a method
+// of type recv, if recv is non-empty) in file, or token.NoPos.
+func findDeclPos(file *ast.File, recv, name string) token.Pos {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name != name {
+				continue
+			}
+			if recv == "" && decl.Recv == nil {
+				return decl.Pos()
+			}
+			if recv != "" && decl.Recv != nil && len(decl.Recv.List) == 1 &&
+				RecvTypeName(decl.Recv.List[0].Type) == recv {
+				return decl.Pos()
+			}
+
+		case *ast.GenDecl:
+			if recv != "" {
+				// Methods are only declared by FuncDecl
+				continue
+			}
+
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.Name == name {
+						return spec.Pos()
+					}
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						if ident.Name == name {
+							return ident.Pos()
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return token.NoPos
+}
+
+// RecvTypeName returns the type name of a method receiver expression,
+// e.g. "Buffer" for "*Buffer".
+func RecvTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return RecvTypeName(expr.X)
+	case *ast.ParenExpr:
+		return RecvTypeName(expr.X)
+	case *ast.IndexExpr:
+		return RecvTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return RecvTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package resolve
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindDecl(t *testing.T) {
+	testCases := []struct {
+		symbol   string
+		wantLine int
+		wantErr  bool
+	}{
+		{symbol: "Greeting", wantLine: 8},
+		{symbol: "Verbose", wantLine: 11},
+		{symbol: "Greeter", wantLine: 14},
+		{symbol: "NewGreeter", wantLine: 20},
+		{symbol: "Greeter.Greet", wantLine: 25},
+		{symbol: "greet", wantLine: 29},
+		{symbol: "Greet", wantErr: true},
+		{symbol: "NewGreeter.Greet", wantErr: true},
+		{symbol: "Missing", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.symbol, func(t *testing.T) {
+			filename, line, err := FindDecl(context.Background(), "../testdata/example", []string{"example.go"}, tc.symbol)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got %s:%d", filename, line)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filename != "example.go" || line != tc.wantLine {
+				t.Errorf("want example.go:%d but got %s:%d", tc.wantLine, filename, line)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package is a locally available pkg.
+type Package struct {
+	ImportPath string
+	// Command is true if the pkg is a command, i.e. a main pkg, for
+	// which godoc serves a command page under /cmd/.
+	Command bool
+}
+
+// ListPackages returns the pkgs available locally,
+// without consulting a godoc http server: the std lib, the pkgs of the
+// module containing dir (if any) and of its dependencies (see
+// DependencyPackages), the pkgs in each GOPATH/src, and the pkgs of the
+// latest version of each other module in the module cache. Thus replace
+// directives and vendor dirs are honoured for the module's dependencies.
+// The commands of the std distribution (e.g. "cmd/go") are also listed,
+// but not its other pkgs beneath "cmd". The returned list is sorted by
+// import path, and has no duplicates.
+//
+// The std lib and the module's pkgs are listed by "go list -find", which
+// doesn't load (or download) the pkgs' dependencies. The other pkgs are
+// found by walking the file system (see walkPackages), as is the module's
+// dir if "go list" fails. The module's build list is read (if available)
+// via "go list -m all".
+func ListPackages(ctx context.Context, dir string) ([]Package, error) {
+	env, err := goEnv(ctx, dir, "GOROOT", "GOMOD", "GOPATH", "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+
+	// The std lib is listed in GOROOT/src, so that it doesn't
+	// depend on the state of the module containing dir.
+	std, err := goListPackages(ctx, filepath.Join(env["GOROOT"], "src"), "std", "cmd")
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]Package{}
+	// The modules whose pkgs are taken from the build list, rather
+	// than from the latest version in the module cache.
+	selected := map[string]bool{}
+	add := func(pkgs []Package) {
+		for _, pkg := range pkgs {
+			set[pkg.ImportPath] = pkg
+		}
+	}
+
+	for _, pkg := range std {
+		// The pkgs beneath "cmd" are not part of std, but
+		// its commands are.
+		if !strings.HasPrefix(pkg.ImportPath, "cmd/") || pkg.Command {
+			add([]Package{pkg})
+		}
+	}
+
+	if gomod := env["GOMOD"]; gomod != "" && gomod != os.DevNull {
+		modPath, err := readModulePath(gomod)
+		if err != nil {
+			return nil, err
+		}
+		pkgs, err := goListPackages(ctx, dir, modPath+"/...")
+		if err != nil {
+			// Not fatal: e.g. go.sum may be out of date.
+			log.Printf("failed to list pkgs of module %s, so walking its dir instead: %v", modPath, err)
+			pkgs = walkPackages(filepath.Dir(gomod), modPath, "")
+		}
+		add(pkgs)
+
+		mods, err := BuildList(ctx, filepath.Dir(gomod))
+		if err != nil {
+			// Not fatal: e.g. go.sum may be out of date.
+			log.Printf("failed to list dependency pkgs of module %s: %v", modPath, err)
+		}
+		add(dependencyPackages(mods))
+		for _, mod := range mods {
+			if mod.Dir != "" {
+				selected[mod.Path] = true
+			}
+		}
+	}
+
+	for _, gopath := range filepath.SplitList(env["GOPATH"]) {
+		add(walkPackages(filepath.Join(gopath, "src"), "", ""))
+	}
+
+	if modcache := env["GOMODCACHE"]; modcache != "" {
+		add(modCachePackages(modcache, selected))
+	}
+
+	var list []Package
+	for _, pkg := range set {
+		list = append(list, pkg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ImportPath < list[j].ImportPath })
+	log.Printf("found %d local pkgs", len(list))
+	return list, nil
+}
+
+// goListPackages returns the pkgs matching patterns, as listed by
+// "go list -find" run in dir. A matching dir that isn't a pkg in the
+// current build context is omitted: e.g. if its files are all test
+// files, or are all excluded by build constraints, or can't be parsed.
+// The go command is run with GOPROXY=off, so that it never downloads
+// modules.
+func goListPackages(ctx context.Context, dir string, patterns ...string) ([]Package, error) {
+	const format = `{{if and (not .Error) (or .GoFiles .CgoFiles)}}{{.ImportPath}} {{.Name}}{{end}}`
+	args := append([]string{"list", "-e", "-find", "-f", format}, patterns...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %v", strings.Join(patterns, " "), err)
+	}
+
+	var pkgs []Package
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pkgs = append(pkgs, Package{ImportPath: fields[0], Command: fields[1] == "main"})
+	}
+	return pkgs, nil
+}
+
+// goEnv returns the values of the go env vars names, as
+// reported by the go command run in dir (if non-empty).
+func goEnv(ctx context.Context, dir string, names ...string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"env"}, names...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env: %v", err)
+	}
+
+	// Trim only the final newline, as the last values may be empty
+	vals := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(vals) != len(names) {
+		return nil, fmt.Errorf("go env: unexpected output: %s", out)
+	}
+
+	env := map[string]string{}
+	for i, name := range names {
+		env[name] = vals[i]
+	}
+	return env, nil
+}
+
+var moduleRegex = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+// readModulePath returns the module path declared by go.mod file gomod.
+func readModulePath(gomod string) (string, error) {
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return "", err
+	}
+
+	m := moduleRegex.FindSubmatch(data)
+	if m == nil {
+		return "", fmt.Errorf("no module path in %s", gomod)
+	}
+	return string(m[1]), nil
+}
+
+// walkPackages returns each pkg beneath root, where root has import
+// path prefix. A pkg is a dir with a non-test Go file that matches the
+// default build context (see build.Context.MatchFile), and that can be
+// parsed. If prefix is non-empty, root is taken to be a module root, and
+// nested modules are ignored. The dir skip (if non-empty) beneath root is
+// ignored. As with the go tool's "./..." pattern, testdata and vendor
+// dirs, and dirs beginning with "." or "_", are ignored.
+func walkPackages(root, prefix, skip string) []Package {
+	var pkgs []Package
+	seen := map[string]bool{}
+	_ = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+
+		if fi.IsDir() {
+			name := fi.Name()
+			if p == root {
+				return nil
+			}
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if skip != "" && p == filepath.Join(root, skip) {
+				return filepath.SkipDir
+			}
+			if prefix != "" {
+				if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+					// A nested module
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+
+		if filepath.Ext(p) != ".go" || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return nil
+		}
+		pkg := path.Join(prefix, filepath.ToSlash(rel))
+		if pkg == "." || seen[pkg] {
+			// Only the first matching file of each pkg is read.
+			return nil
+		}
+
+		if ok, err := build.Default.MatchFile(filepath.Dir(p), fi.Name()); err != nil || !ok {
+			return nil
+		}
+		name, ok := packageName(p)
+		if !ok {
+			return nil
+		}
+
+		seen[pkg] = true
+		pkgs = append(pkgs, Package{ImportPath: pkg, Command: name == "main"})
+		return nil
+	})
+	return pkgs
+}
+
+// packageName returns the pkg name declared by Go file filename,
+// or false if the file can't be parsed.
+func packageName(filename string) (name string, ok bool) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", false
+	}
+	return file.Name.Name, true
+}
+
+// modCachePackages returns the import paths of the pkgs of the latest
+// version of each module in the module cache, other than the modules
+// in skip.
+func modCachePackages(modcache string, skip map[string]bool) []Package {
+	// The module cache has a dir for each module version, e.g.
+	// "github.com/!puerkito!bio/goquery@v1.5.0", where uppercase letters
+	// are escaped as "!" followed by the lowercase letter.
+	latest := map[string]string{} // module path -> dir of latest version
+	versions := map[string]string{}
+
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return
+		}
+
+		for _, fi := range fis {
+			if !fi.IsDir() {
+				continue
+			}
+			name := fi.Name()
+			if rel == "" && name == "cache" {
+				// The download cache
+				continue
+			}
+
+			i := strings.IndexRune(name, '@')
+			if i < 0 {
+				walk(filepath.Join(dir, name), path.Join(rel, name))
+				continue
+			}
+
+			mod := unescapeModPath(path.Join(rel, name[:i]))
+			if skip[mod] {
+				continue
+			}
+			version := name[i+1:]
+			if v, ok := versions[mod]; !ok || compareVersions(version, v) > 0 {
+				versions[mod] = version
+				latest[mod] = filepath.Join(dir, name)
+			}
+		}
+	}
+	walk(modcache, "")
+
+	var pkgs []Package
+	for mod, dir := range latest {
+		pkgs = append(pkgs, walkPackages(dir, mod, "")...)
+	}
+	return pkgs
+}
+
+// unescapeModPath reverses the module cache's escaping of uppercase
+// letters, e.g. "github.com/!puerkito!bio" is "github.com/PuerkitoBio".
+func unescapeModPath(p string) string {
+	var sb strings.Builder
+	bang := false
+	for _, r := range p {
+		switch {
+		case r == '!':
+			bang = true
+			continue
+		case bang && r >= 'a' && r <= 'z':
+			r -= 'a' - 'A'
+		}
+		bang = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// compareVersions compares the semantic versions a and b, e.g. "v1.2.3"
+// and "v1.10.0-rc.1", returning -1, 0 or +1. Prerelease versions sort
+// before the release, and build metadata is ignored.
+func compareVersions(a, b string) int {
+	aNums, aPre := splitVersion(a)
+	bNums, bPre := splitVersion(b)
+
+	for i := 0; i < 3; i++ {
+		if aNums[i] != bNums[i] {
+			if aNums[i] < bNums[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitVersion returns the major, minor and patch numbers, and the
+// prerelease, of version.
+func splitVersion(version string) (nums [3]int, prerelease string) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexRune(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexRune(version, '-'); i >= 0 {
+		version, prerelease = version[:i], version[i+1:]
+	}
+
+	for i, s := range strings.SplitN(version, ".", 3) {
+		nums[i], _ = strconv.Atoi(s)
+	}
+	return nums, prerelease
+}
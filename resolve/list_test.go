@@ -0,0 +1,199 @@
+package resolve
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v1.2.3", b: "v1.2.3", want: 0},
+		{a: "v1.2.3", b: "v1.2.4", want: -1},
+		{a: "v1.10.0", b: "v1.9.0", want: 1},
+		{a: "v2.0.0+incompatible", b: "v1.9.9", want: 1},
+		{a: "v1.0.0-rc.1", b: "v1.0.0", want: -1},
+		{a: "v0.0.0-20190101000000-abcdef", b: "v0.0.0-20200101000000-abcdef", want: -1},
+	}
+
+	for _, tc := range testCases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q): want %d but got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+func TestUnescapeModPath(t *testing.T) {
+	const want = "github.com/PuerkitoBio/goquery"
+	if got := unescapeModPath("github.com/!puerkito!bio/goquery"); got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+}
+
+func TestModCachePackages(t *testing.T) {
+	modcache, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(modcache)
+
+	files := []string{
+		"cache/download/github.com/my/mod/@v/v1.0.0.zip",
+		"github.com/my/mod@v1.0.0/old/old.go",
+		"github.com/my/mod@v1.2.0/mod.go",
+		"github.com/my/mod@v1.2.0/sub/sub.go",
+		"github.com/my/mod@v1.2.0/sub/testdata/data.go",
+		"github.com/my/mod@v1.2.0/internal/.hidden/hidden.go",
+		"github.com/!big!corp/lib@v0.1.0/lib.go",
+		"github.com/!big!corp/lib@v0.1.0/README.md",
+	}
+	contents := map[string]string{}
+	for _, f := range files {
+		contents[f] = "package x\n"
+	}
+	writeFiles(t, modcache, contents)
+
+	got := importPaths(modCachePackages(modcache, nil))
+	want := []string{"github.com/BigCorp/lib", "github.com/my/mod", "github.com/my/mod/sub"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+
+	// Skipped modules, e.g. those in the build list, are ignored
+	got = importPaths(modCachePackages(modcache, map[string]bool{"github.com/my/mod": true}))
+	want = []string{"github.com/BigCorp/lib"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	got, err := readModulePath("../go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "github.com/neilotoole/gohdoc"
+	if got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+}
+
+// importPaths returns the sorted import paths of pkgs.
+func importPaths(pkgs []Package) []string {
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.ImportPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWalkPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                  "module example.com\n",
+		"main.go":                 "package main\n",
+		"a/a.go":                  "package a\n",
+		"a/b/b.go":                "package b\n",
+		"a/testdata/t.go":         "package t\n",
+		"vendor/v.com/v/v.go":     "package v\n",
+		".hidden/h.go":            "package h\n",
+		"_under/u.go":             "package u\n",
+		"nested/go.mod":           "module example.com/nested\n",
+		"nested/n.go":             "package nested\n",
+		"skipped/s.go":            "package s\n",
+		"doc/README.md":           "not a pkg\n",
+		"a/b/nogo/data/data.json": "{}\n",
+	})
+
+	pkgs := walkPackages(dir, "example.com", "skipped")
+	got := importPaths(pkgs)
+
+	// The root pkg and subdirs are found; testdata, vendor, hidden and
+	// skip dirs, and nested modules are ignored
+	want := []string{"example.com", "example.com/a", "example.com/a/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+
+	// Only the root pkg is a command
+	for _, pkg := range pkgs {
+		if want := pkg.ImportPath == "example.com"; pkg.Command != want {
+			t.Errorf("%s: want command %v but got %v", pkg.ImportPath, want, pkg.Command)
+		}
+	}
+}
+
+func TestWalkPackagesCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"cmd/a/a_test.go":    "package a_test\n",
+		"cmd/a/main.go":      "// Copyright\n\n// Command a does things.\npackage main\n\nfunc main() {}\n",
+		"lib/a_gen.go":       "//go:build ignore\n\npackage main\n",
+		"lib/b_gen.go":       "// +build ignore\n\npackage main\n",
+		"lib/lib.go":         "package lib\n",
+		"broken/broken.go":   "not go\n",
+		"onlytest/x_test.go": "package main\n",
+		"ignored/gen.go":     "//go:build ignore\n\npackage main\n",
+	})
+
+	// Dirs whose files are all test files, excluded by build
+	// constraints, or unparseable are not pkgs.
+	want := []Package{
+		{ImportPath: "example.com/cmd/a", Command: true},
+		{ImportPath: "example.com/lib"},
+	}
+	got := walkPackages(dir, "example.com", "")
+	sort.Slice(got, func(i, j int) bool { return got[i].ImportPath < got[j].ImportPath })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
+
+func TestGoListPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"go.mod":             "module example.com\n\ngo 1.16\n",
+		"cmd/a/main.go":      "package main\n\nfunc main() {}\n",
+		"lib/lib.go":         "package lib\n\nimport _ \"example.net/not/downloaded\"\n",
+		"broken/broken.go":   "not go\n",
+		"onlytest/x_test.go": "package main\n",
+		"ignored/gen.go":     "//go:build ignore\n\npackage main\n",
+	})
+
+	got, err := goListPackages(context.Background(), dir, "example.com/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].ImportPath < got[j].ImportPath })
+
+	// A pkg with a dependency that isn't available is still listed
+	want := []Package{
+		{ImportPath: "example.com/cmd/a", Command: true},
+		{ImportPath: "example.com/lib"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
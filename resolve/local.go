@@ -0,0 +1,57 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Local locates the source of the pkg referred to by arg, without
+// consulting a godoc http server. The arg is treated as a dir if such
+// a dir exists, otherwise it is treated as an import path (relative
+// to cwd). The pkg's files are those that match bctx, e.g. for a
+// platform other than the host.
+func Local(ctx context.Context, bctx *build.Context, cwd string, arg Arg) (*build.Package, error) {
+	var bp *build.Package
+	var err error
+
+	dir := filepath.FromSlash(arg.Path)
+	if fi, statErr := os.Stat(dir); statErr == nil && fi.IsDir() {
+		log.Printf("importing pkg from dir: %s", dir)
+		bp, err = bctx.ImportDir(dir, 0)
+	} else {
+		log.Printf("importing pkg: %s", arg.Pkg)
+		bp, err = bctx.Import(arg.Pkg, cwd, 0)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pkg %s: %v", arg.Pkg, err)
+	}
+
+	if bp.ImportPath == "." {
+		// In module mode, build.ImportDir doesn't determine the import path.
+		bp.ImportPath, err = goListImportPath(ctx, bp.Dir)
+		if err != nil {
+			log.Printf("failed to determine import path of %s: %v", bp.Dir, err)
+			bp.ImportPath = bp.Name
+		}
+	}
+	return bp, nil
+}
+
+// goListImportPath returns the import path of the pkg in dir,
+// as reported by "go list".
+func goListImportPath(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-f", "{{.ImportPath}}")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
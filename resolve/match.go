@@ -0,0 +1,115 @@
+package resolve
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches returns the set of pkg names that match s, with the
+// best match first. The "best match" algorithm is pretty trivial.
+// If there's an exact match of s against pkgs, then exactMatch is returned
+// true, and matches has minimum length 1 (although it may be larger).
+func Matches(pkgs []string, s string) (matches []string, exactMatch bool) {
+	if len(s) == 0 || len(pkgs) == 0 {
+		return matches, false
+	}
+
+	// We match on whether s is a suffix, prefix, or is contained in pkg
+	var sufMatches, preMatches, containMatches []string
+
+	for _, pkg := range pkgs {
+		switch matchScore(pkg, s) {
+		case scoreExact:
+			exactMatch = true
+		case scoreSuffix:
+			sufMatches = append(sufMatches, pkg)
+		case scorePrefix:
+			preMatches = append(preMatches, pkg)
+		case scoreContains:
+			containMatches = append(containMatches, pkg)
+		}
+	}
+
+	sort.Strings(sufMatches)
+	sort.Strings(preMatches)
+	sort.Strings(containMatches)
+
+	if exactMatch {
+		// If there's an exact match, it should be the first result
+		matches = append(matches, s)
+	}
+	matches = append(matches, sufMatches...)
+	matches = append(matches, preMatches...)
+	matches = append(matches, containMatches...)
+
+	return matches, exactMatch
+
+}
+
+// The scores of the ways that a pkg can match a search term, as
+// per Matches. The higher the score, the better the match.
+const (
+	scoreNone = iota
+	scoreContains
+	scorePrefix
+	scoreSuffix
+	scoreExact
+)
+
+// scoreNames are the names of the match scores, e.g. for a Trace.
+var scoreNames = map[int]string{
+	scoreNone:     "none",
+	scoreContains: "contains",
+	scorePrefix:   "prefix",
+	scoreSuffix:   "suffix",
+	scoreExact:    "exact",
+}
+
+// matchScore returns the score of pkg as a match for search term s.
+func matchScore(pkg, s string) int {
+	switch {
+	case pkg == s:
+		return scoreExact
+	case strings.HasSuffix(pkg, s):
+		return scoreSuffix
+	case strings.HasPrefix(pkg, s):
+		return scorePrefix
+	case strings.Contains(pkg, s):
+		return scoreContains
+	}
+	return scoreNone
+}
+
+// Filter returns the pkgs that match any of patterns, or all
+// pkgs if patterns is empty.
+func Filter(pkgs []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return pkgs
+	}
+
+	var res []string
+	for _, pkg := range pkgs {
+		for _, pattern := range patterns {
+			if MatchPattern(pattern, pkg) {
+				res = append(res, pkg)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// MatchPattern reports whether pkg matches pattern. As with the go
+// tool, "..." in a pattern matches any string, and a trailing "/..."
+// also matches the pkg itself, e.g. "net/..." matches "net" and "net/http".
+func MatchPattern(pattern, pkg string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+
+	ok, err := regexp.MatchString("^"+re+"$", pkg)
+	return err == nil && ok
+}
@@ -0,0 +1,70 @@
+package resolve
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	pkgs := []string{"bytes", "encoding/json", "encoding/xml", "github.com/my/json", "jsonx"}
+
+	testCases := []struct {
+		s         string
+		want      []string
+		wantExact bool
+	}{
+		{s: "", want: nil},
+		{s: "bytes", want: []string{"bytes"}, wantExact: true},
+		{s: "byt", want: []string{"bytes"}},
+		{s: "json", want: []string{"encoding/json", "github.com/my/json", "jsonx"}},
+		{s: "encoding/", want: []string{"encoding/json", "encoding/xml"}},
+		{s: "nothing", want: nil},
+	}
+
+	for _, tc := range testCases {
+		got, gotExact := Matches(pkgs, tc.s)
+		if !reflect.DeepEqual(got, tc.want) || gotExact != tc.wantExact {
+			t.Errorf("Matches(%q): want %v %v but got %v %v", tc.s, tc.want, tc.wantExact, got, gotExact)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		pkg     string
+		want    bool
+	}{
+		{pattern: "fmt", pkg: "fmt", want: true},
+		{pattern: "fmt", pkg: "fmtx", want: false},
+		{pattern: "net/...", pkg: "net", want: true},
+		{pattern: "net/...", pkg: "net/http", want: true},
+		{pattern: "net/...", pkg: "netx", want: false},
+		{pattern: "...", pkg: "encoding/json", want: true},
+		{pattern: "encoding/...json", pkg: "encoding/json", want: true},
+		{pattern: "github.com/my/...", pkg: "github.com/my/pkg/sub", want: true},
+		{pattern: "github.com/my/...", pkg: "github.com/other", want: false},
+	}
+
+	for _, tc := range testCases {
+		got := MatchPattern(tc.pattern, tc.pkg)
+		if got != tc.want {
+			t.Errorf("MatchPattern(%q, %q): want %v but got %v", tc.pattern, tc.pkg, tc.want, got)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	pkgs := []string{"fmt", "net", "net/http", "net/url", "encoding/json"}
+
+	got := Filter(pkgs, nil)
+	if !reflect.DeepEqual(got, pkgs) {
+		t.Errorf("want %v but got %v", pkgs, got)
+	}
+
+	got = Filter(pkgs, []string{"net/...", "fmt", "nothing"})
+	want := []string{"fmt", "net", "net/http", "net/url"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
@@ -0,0 +1,218 @@
+package resolve
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Module is a module in the build list of the main module.
+type Module struct {
+	Path    string
+	Version string
+	// Replace is the module that replaces this module, as per a replace
+	// directive in go.mod, or nil. A replacement with a local dir has the
+	// dir (as written in go.mod) as its Path, and no Version.
+	Replace *Module
+	// Dir is the dir holding the module's source: for a dependency, its
+	// dir in the module cache, or the dir of its replacement, or its dir
+	// beneath vendor (in vendor mode). It is empty if the module hasn't
+	// been downloaded.
+	Dir  string
+	Main bool
+}
+
+// BuildList returns the modules in the build list of the module
+// containing dir, as reported by "go list -m all". That is, the main
+// module, and each dependency at the version selected by go.mod, with
+// replace directives applied. Only the local module cache is consulted,
+// so a dependency that hasn't been downloaded has no Dir. The module's
+// go.mod and go.sum are never modified. In vendor mode (see vendorMode),
+// the build list is instead read from vendor/modules.txt, as the go
+// command does, and each dependency's Dir is its dir beneath vendor.
+func BuildList(ctx context.Context, dir string) ([]Module, error) {
+	env, err := goEnv(ctx, dir, "GOMOD", "GOFLAGS")
+	if err != nil {
+		return nil, err
+	}
+	if gomod := env["GOMOD"]; gomod != "" && gomod != os.DevNull && vendorMode(gomod, env["GOFLAGS"]) {
+		return vendorBuildList(filepath.Dir(gomod))
+	}
+
+	// The build list is read without using the network: a module whose
+	// go.mod isn't in the module cache is listed without a Dir, rather
+	// than being downloaded (see -e). The user's GOFLAGS are kept, but
+	// -mod=readonly is appended (the last -mod flag wins), so that go.mod
+	// and go.sum are left as they are, e.g. if go.mod is untidy.
+	goflags := strings.TrimSpace(env["GOFLAGS"] + " -mod=readonly")
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-e", "-json", "all")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS="+goflags, "GOPROXY=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -m all: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// The output is a stream of JSON objects, not an array.
+	var mods []Module
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var mod Module
+		err = dec.Decode(&mod)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("go list -m all: %v", err)
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+// VendorMode returns true if the go command builds the module containing
+// dir from its vendor dir (see vendorMode). It returns false if dir is
+// not in a module.
+func VendorMode(ctx context.Context, dir string) (bool, error) {
+	env, err := goEnv(ctx, dir, "GOMOD", "GOFLAGS")
+	if err != nil {
+		return false, err
+	}
+	gomod := env["GOMOD"]
+	if gomod == "" || gomod == os.DevNull {
+		return false, nil
+	}
+	return vendorMode(gomod, env["GOFLAGS"]), nil
+}
+
+var goDirectiveRegex = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+(\.\d+)?)\s*$`)
+
+// vendorMode returns true if the go command builds the module with
+// go.mod file gomod from its vendor dir, given the value of GOFLAGS.
+// As with the go command, -mod=vendor enables vendor mode, -mod=mod or
+// -mod=readonly disables it, and otherwise vendor mode is enabled if
+// vendor/modules.txt exists and go.mod declares go 1.14 or later.
+func vendorMode(gomod, goflags string) bool {
+	for _, flag := range strings.Fields(goflags) {
+		switch strings.TrimLeft(flag, "-") {
+		case "mod=vendor":
+			return true
+		case "mod=mod", "mod=readonly":
+			return false
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(gomod), "vendor", "modules.txt")); err != nil {
+		return false
+	}
+
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return false
+	}
+	m := goDirectiveRegex.FindSubmatch(data)
+	return m != nil && compareVersions("v"+string(m[1]), "v1.14.0") >= 0
+}
+
+// vendorBuildList returns the main module at dir, and the modules
+// recorded in dir/vendor/modules.txt. Lines of that file take the form:
+//
+//	# example.com/dep v1.2.0 => example.com/fork v1.2.1
+//	## explicit; go 1.16
+//	example.com/dep
+//	example.com/dep/sub
+//
+// where the replacement is optional, and the unmarked lines are the
+// vendored pkgs of the preceding module.
+func vendorBuildList(dir string) ([]Module, error) {
+	modPath, err := readModulePath(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	mods := []Module{{Path: modPath, Dir: dir, Main: true}}
+
+	vendorDir := filepath.Join(dir, "vendor")
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+
+		var mod Module
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		if i := indexOf(fields, "=>"); i >= 0 {
+			repl := &Module{Path: fields[i+1]}
+			if len(fields) > i+2 {
+				repl.Version = fields[i+2]
+			}
+			mod.Replace = repl
+			fields = fields[:i]
+		}
+		if len(fields) != 2 {
+			// A replacement of all versions of a module, recorded
+			// only for consistency checking.
+			continue
+		}
+		mod.Path, mod.Version = fields[0], fields[1]
+		mod.Dir = filepath.Join(vendorDir, filepath.FromSlash(mod.Path))
+		mods = append(mods, mod)
+	}
+	if err = sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", f.Name(), err)
+	}
+	return mods, nil
+}
+
+// indexOf returns the index of the first occurrence of s in a, or -1.
+func indexOf(a []string, s string) int {
+	for i := range a {
+		if a[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// DependencyPackages returns the pkgs of each
+// (downloaded) dependency in the build list of the module containing dir.
+// The pkgs are those of the version selected by go.mod (or of its
+// replacement, or of its vendored copy), which may not be the latest
+// version in the module cache.
+func DependencyPackages(ctx context.Context, dir string) ([]Package, error) {
+	mods, err := BuildList(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return dependencyPackages(mods), nil
+}
+
+// dependencyPackages returns the pkgs of each dependency in mods
+// that has a Dir.
+func dependencyPackages(mods []Module) []Package {
+	var pkgs []Package
+	for _, mod := range mods {
+		if mod.Main || mod.Dir == "" {
+			continue
+		}
+		pkgs = append(pkgs, walkPackages(mod.Dir, mod.Path, "")...)
+	}
+	return pkgs
+}
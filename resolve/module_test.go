@@ -0,0 +1,198 @@
+package resolve
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes each file (with content) beneath dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for f, content := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDependencyPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The dependency is replaced by a local dir, so that the test
+	// doesn't need to download anything.
+	writeFiles(t, dir, map[string]string{
+		"main/go.mod":       "module example.com/main\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n",
+		"main/main.go":      "package main\n",
+		"dep/go.mod":        "module example.com/dep\n",
+		"dep/dep.go":        "package dep\n",
+		"dep/sub/sub.go":    "package sub\n",
+		"dep/testdata/x":    "",
+		"dep/_skip/x.go":    "package skip\n",
+		"dep/nested/go.mod": "module example.com/dep/nested\n",
+		"dep/nested/n.go":   "package nested\n",
+	})
+
+	pkgs, err := DependencyPackages(context.Background(), filepath.Join(dir, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := importPaths(pkgs)
+
+	want := []string{"example.com/dep", "example.com/dep/sub"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
+
+func TestBuildListOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The dependency isn't in the module cache, and is not downloaded
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/main\n\ngo 1.16\n\nrequire example.net/notcached v1.0.0\n",
+		"main.go": "package main\n",
+	})
+
+	mods, err := BuildList(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Module{
+		{Path: "example.com/main", Dir: dir, Main: true},
+		{Path: "example.net/notcached", Version: "v1.0.0"},
+	}
+	if !reflect.DeepEqual(mods, want) {
+		t.Errorf("want %+v but got %+v", want, mods)
+	}
+}
+
+func TestBuildListReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Even if the user's GOFLAGS allow go.mod to be updated
+	defer setenv(t, map[string]string{"GOFLAGS": "-mod=mod"})()
+
+	// The go command would add a go directive to this go.mod, if
+	// allowed to.
+	gomod := "module example.com/main\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n"
+	writeFiles(t, dir, map[string]string{
+		"main/go.mod":  gomod,
+		"main/main.go": "package main\n",
+		"dep/go.mod":   "module example.com/dep\n",
+		"dep/dep.go":   "package dep\n",
+	})
+
+	mods, err := BuildList(context.Background(), filepath.Join(dir, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 2 {
+		t.Errorf("want main module and dep but got %+v", mods)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(dir, "main", "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != gomod {
+		t.Errorf("go.mod was modified:\n%s", got)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "main", "go.sum")); !os.IsNotExist(err) {
+		t.Errorf("go.sum should not be created, but got %v", err)
+	}
+}
+
+func TestVendorMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"old/go.mod":                "module example.com/old\n\ngo 1.13\n",
+		"old/vendor/modules.txt":    "",
+		"new/go.mod":                "module example.com/new\n\ngo 1.21.0\n",
+		"new/vendor/modules.txt":    "",
+		"novendor/go.mod":           "module example.com/novendor\n\ngo 1.16\n",
+		"novendor/vendor/README.md": "",
+	})
+
+	testCases := []struct {
+		mod     string
+		goflags string
+		want    bool
+	}{
+		{mod: "old", want: false},
+		{mod: "old", goflags: "-mod=vendor", want: true},
+		{mod: "new", want: true},
+		{mod: "new", goflags: "-v -mod=mod", want: false},
+		{mod: "new", goflags: "--mod=readonly", want: false},
+		{mod: "novendor", want: false},
+	}
+
+	for _, tc := range testCases {
+		got := vendorMode(filepath.Join(dir, tc.mod, "go.mod"), tc.goflags)
+		if got != tc.want {
+			t.Errorf("%s %q: want %v but got %v", tc.mod, tc.goflags, tc.want, got)
+		}
+	}
+}
+
+func TestDependencyPackagesVendor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer setenv(t, map[string]string{"GOFLAGS": ""})()
+
+	// Only the vendored pkgs (not those of the module cache or of the
+	// replacement dir) are used to build the module.
+	writeFiles(t, dir, map[string]string{
+		"main/go.mod": "module example.com/main\n\ngo 1.16\n\n" +
+			"require (\n\texample.com/dep v1.0.0\n\texample.com/fork v1.0.0\n)\n\n" +
+			"replace example.com/dep => ../dep\n",
+		"main/main.go": "package main\n",
+		"main/vendor/modules.txt": "# example.com/dep v1.0.0 => ../dep\n## explicit\nexample.com/dep\n" +
+			"# example.com/fork v1.0.0 => example.com/other v1.1.0\n## explicit\nexample.com/fork/sub\n",
+		"main/vendor/example.com/dep/dep.go":      "package dep\n",
+		"main/vendor/example.com/fork/sub/sub.go": "package sub\n",
+		"dep/go.mod":           "module example.com/dep\n",
+		"dep/dep.go":           "package dep\n",
+		"dep/unused/unused.go": "package unused\n",
+	})
+
+	mods, err := BuildList(context.Background(), filepath.Join(dir, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 3 || mods[2].Replace == nil || mods[2].Replace.Path != "example.com/other" {
+		t.Errorf("unexpected build list: %+v", mods)
+	}
+
+	got := importPaths(dependencyPackages(mods))
+	want := []string{"example.com/dep", "example.com/fork/sub"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
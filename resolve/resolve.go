@@ -0,0 +1,208 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+)
+
+// Result is the outcome of resolving an Arg against a godoc http
+// server's pkg list.
+type Result struct {
+	// Pkg is the server pkg, e.g. "encoding/json".
+	Pkg string
+	// Fragment is the (possibly empty) fragment from the arg, e.g. "Println".
+	Fragment string
+	// Symbol is the (possibly empty) go doc-style symbol from the arg,
+	// e.g. "client.do". The caller determines the fragment by matching
+	// Symbol against the ids of the pkg page (see MatchSymbol).
+	Symbol string
+	// Term is the pkg search term derived from the arg.
+	Term string
+	// PossibleMatches is non-empty if Pkg was not an exact match, but
+	// was picked from the set of possible matches for Term.
+	PossibleMatches []string
+}
+
+// PageOKFunc reports whether the server page for pkg is available.
+// If retry is true, the func should keep trying for a short while
+// before giving up. See server.Server.PkgPageOK.
+type PageOKFunc func(ctx context.Context, pkg string, retry bool) (bool, error)
+
+// Resolve determines which of pkgs (the server pkg list) arg refers
+// to, verifying via pageOK that the pkg page is available on the server.
+// Besides a (possibly partial) pkg name or path, arg may take the form
+// of a go doc arg, e.g. "json.Unmarshal", "http.Client.Do", or "Client.Do"
+// for a symbol of the current pkg. Such a symbol is returned in
+// Result.Symbol, for the caller to match against the pkg page's ids.
+func Resolve(ctx context.Context, arg Arg, pkgs []string, pageOK PageOKFunc) (*Result, error) {
+	res, _, err := Explain(ctx, arg, pkgs, pageOK)
+	return res, err
+}
+
+// Explain is like Resolve, but also returns a Trace of how arg was
+// resolved. The Trace is returned even if an error is returned.
+func Explain(ctx context.Context, arg Arg, pkgs []string, pageOK PageOKFunc) (*Result, *Trace, error) {
+	tr := &Trace{Arg: arg}
+	res, err := explain(ctx, tr, arg, pkgs, pageOK)
+	tr.Result = res
+	return res, tr, err
+}
+
+// explain does the work of Explain, recording each step in tr.
+func explain(ctx context.Context, tr *Trace, arg Arg, pkgs []string, pageOK PageOKFunc) (*Result, error) {
+	pth, pkg, fragment := arg.Path, arg.Pkg, arg.Fragment
+
+	verify := func(c *Candidate, retry bool) (bool, error) {
+		c.PageOK, c.Err = pageOK(ctx, c.Pkg, retry)
+		c.Verified = c.Err == nil
+		return c.PageOK, c.Err
+	}
+
+	// Try the path-based approach first.
+	step := tr.step("path")
+	if serverPkg := pathPkg(pth, pkgs); serverPkg != "" {
+		log.Println("found in pkg list, will attempt to verify page on server:", serverPkg)
+		step.Note = fmt.Sprintf("pkg %s matches a trailing part of path %s", serverPkg, pth)
+
+		ok, err := verify(step.candidate(serverPkg, "path", 0), true)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("should have been able to open pkg page %s but failed: %v",
+				serverPkg, err)
+		}
+
+		return &Result{Pkg: serverPkg, Fragment: fragment, Symbol: arg.Symbol, Term: pkg}, nil
+	}
+	step.Note = fmt.Sprintf("no pkg matches a trailing part of path %s", pth)
+
+	// We weren't able to match the path (or subsections of it) against
+	// pkgs, so we'll search for the pkg term.
+	// When we get this far, we could be searching for partial
+	// names like "byt", or "encoding/jso".
+	step = tr.step("exact match")
+	matches, exactMatch := Matches(pkgs, pkg)
+	if exactMatch {
+		// If we've got an exact match, we only want to open that page
+		step.Note = fmt.Sprintf("pkg %s matches search term %s", matches[0], pkg)
+		ok, err := verify(step.candidate(matches[0], scoreNames[scoreExact], scoreExact), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// shouldn't happen
+			return nil, fmt.Errorf("should have been able to open this, but it seems not to exist: %s", matches[0])
+		}
+
+		return &Result{Pkg: matches[0], Fragment: fragment, Symbol: arg.Symbol, Term: pkg}, nil
+	}
+	step.Note = fmt.Sprintf("no pkg is named %s", pkg)
+
+	// The args "json.Unmarshal" or "http.Client.Do" are go doc-style
+	// pkg.Sym args. As with go doc, the pkg part must match exactly.
+	isDocArg := fragment == "" && arg.Symbol == ""
+	if isDocArg {
+		step = tr.step("go doc-style arg")
+		splits := docSplits(pkg)
+		step.Note = fmt.Sprintf("%s has %d possible pkg.Sym split(s)", pkg, len(splits))
+		for _, split := range splits {
+			docPkg := docPkg(pkgs, split.pkg)
+			if docPkg == "" {
+				continue
+			}
+
+			log.Printf("found pkg %s for go doc-style arg %s", docPkg, pkg)
+			ok, err := verify(step.candidate(docPkg, "go doc", 0), true)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return &Result{Pkg: docPkg, Symbol: split.sym, Term: split.pkg}, nil
+			}
+		}
+	}
+
+	// We don't have an exact match, so we'll check which of the
+	// best-ranked possible matches we can open the page of.
+	step = tr.step("possible matches")
+	step.Note = fmt.Sprintf("search term %s matches %d pkg(s)", pkg, len(matches))
+	for _, match := range matches {
+		score := matchScore(match, pkg)
+		step.candidate(match, scoreNames[score], score)
+	}
+	top := step.Candidates
+	if len(top) > maxVerify {
+		step.Note += fmt.Sprintf("; verifying the top %d", maxVerify)
+		top = top[:maxVerify]
+	}
+	if len(top) > 0 {
+		c, err := verifyCandidates(ctx, top, pageOK)
+		if c != nil {
+			return &Result{Pkg: c.Pkg, Fragment: fragment, Symbol: arg.Symbol, Term: pkg, PossibleMatches: matches}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Finally, as with go doc, the arg may be a symbol of the current pkg,
+	// e.g. "Client.Do". As the arg has no slash, the current pkg's path
+	// is the parent of the arg's path.
+	if isDocArg && !strings.Contains(pkg, "/") && isSymbol(pkg) {
+		step = tr.step("symbol of current pkg")
+		step.Note = fmt.Sprintf("no pkg matches a trailing part of path %s", path.Dir(pth))
+		if cwdPkg := pathPkg(path.Dir(pth), pkgs); cwdPkg != "" {
+			log.Printf("treating arg %s as a symbol of current pkg %s", pkg, cwdPkg)
+			step.Note = fmt.Sprintf("treating %s as a symbol of current pkg %s", pkg, cwdPkg)
+			ok, err := verify(step.candidate(cwdPkg, "path", 0), true)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return &Result{Pkg: cwdPkg, Symbol: pkg, Term: pkg}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find in server pkg list: %s", pkg)
+}
+
+// pathPkg returns the pkg (one of pkgs) that matches the longest
+// trailing part of path pth, or empty string.
+func pathPkg(pth string, pkgs []string) string {
+	// pth looks something like /go/src/github.com/neilotoole/gohdoc
+	// We'll iteratively look for a package that matches the path, trimming
+	// a front segment each time. That is, we'll search for:
+	//
+	//   go/src/github.com/neilotoole/gohdoc
+	//   src/github.com/neilotoole/gohdoc
+	//   github.com/neilotoole/gohdoc
+	//   neilotoole/gohdoc
+	//   gohdoc
+
+	// strip the leading slash, we don't need it
+	pth = strings.TrimPrefix(pth, "/")
+
+	parts := strings.Split(pth, "/")
+	for {
+		// reconstruct the path
+		findPkg := strings.Join(parts, "/")
+		log.Println("checking if pkg is listed:", findPkg)
+
+		for _, serverPkg := range pkgs {
+			if findPkg == serverPkg {
+				return serverPkg
+			}
+		}
+
+		if len(parts) == 1 {
+			return ""
+		}
+
+		parts = parts[1:]
+	}
+}
@@ -0,0 +1,189 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	pkgs := []string{"bytes", "encoding/json", "github.com/neilotoole/gohdoc", "github.com/my/jsonutil"}
+	// The page for github.com/my/jsonutil is not available
+	pageOK := func(ctx context.Context, pkg string, retry bool) (bool, error) {
+		return pkg != "github.com/my/jsonutil", nil
+	}
+
+	testCases := []struct {
+		arg     Arg
+		want    *Result
+		wantErr bool
+	}{
+		{
+			arg:  Arg{Path: "/go/src/github.com/neilotoole/gohdoc", Pkg: "gohdoc", Fragment: "Frag"},
+			want: &Result{Pkg: "github.com/neilotoole/gohdoc", Fragment: "Frag", Term: "gohdoc"},
+		},
+		{
+			arg:  Arg{Path: "/home/me/bytes", Pkg: "bytes"},
+			want: &Result{Pkg: "bytes", Term: "bytes"},
+		},
+		{
+			arg:  Arg{Path: "/home/me/json", Pkg: "json"},
+			want: &Result{Pkg: "encoding/json", Term: "json", PossibleMatches: []string{"encoding/json", "github.com/my/jsonutil"}},
+		},
+		{
+			arg:     Arg{Path: "/home/me/jsonutil", Pkg: "jsonutil"},
+			wantErr: true,
+		},
+		{
+			arg:  Arg{Path: "/home/me/bytes", Pkg: "bytes", Symbol: "buffer.len"},
+			want: &Result{Pkg: "bytes", Symbol: "buffer.len", Term: "bytes"},
+		},
+		{
+			arg:  Arg{Path: "/home/me/json.Unmarshal", Pkg: "json.Unmarshal"},
+			want: &Result{Pkg: "encoding/json", Symbol: "Unmarshal", Term: "json"},
+		},
+		{
+			arg:  Arg{Path: "/home/me/encoding/json.Decoder.Decode", Pkg: "encoding/json.Decoder.Decode"},
+			want: &Result{Pkg: "encoding/json", Symbol: "Decoder.Decode", Term: "encoding/json"},
+		},
+		{
+			arg:  Arg{Path: "/go/src/github.com/neilotoole/gohdoc/App.run", Pkg: "App.run"},
+			want: &Result{Pkg: "github.com/neilotoole/gohdoc", Symbol: "App.run", Term: "App.run"},
+		},
+		{
+			// Not a go doc-style arg, as it has a fragment
+			arg:     Arg{Path: "/home/me/json.Unmarshal", Pkg: "json.Unmarshal", Fragment: "Frag"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := Resolve(context.Background(), tc.arg, pkgs, pageOK)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error but got %+v", tc.arg.Pkg, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.arg.Pkg, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: want %+v but got %+v", tc.arg.Pkg, tc.want, got)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	pkgs := []string{"encoding/json", "github.com/my/jsonutil", "github.com/my/json"}
+	// The page for github.com/my/json is not available
+	pageOK := func(ctx context.Context, pkg string, retry bool) (bool, error) {
+		return pkg != "github.com/my/json", nil
+	}
+
+	arg := Arg{Path: "/home/me/json", Pkg: "json"}
+	res, tr, err := Explain(context.Background(), arg, pkgs, pageOK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Result != res || res.Pkg != "encoding/json" {
+		t.Fatalf("want encoding/json as trace result but got %+v", tr.Result)
+	}
+
+	var strategies []string
+	for _, step := range tr.Steps {
+		strategies = append(strategies, step.Strategy)
+	}
+	wantStrategies := []string{"path", "exact match", "go doc-style arg", "possible matches"}
+	if !reflect.DeepEqual(strategies, wantStrategies) {
+		t.Errorf("want strategies %v but got %v", wantStrategies, strategies)
+	}
+
+	// The suffix matches rank above the contains match. The top
+	// candidates are verified concurrently, until the best-ranked OK
+	// candidate is known: the others may or may not be verified.
+	wantCandidates := []Candidate{
+		{Pkg: "encoding/json", Match: "suffix", Score: scoreSuffix, Verified: true, PageOK: true},
+		{Pkg: "github.com/my/json", Match: "suffix", Score: scoreSuffix, Verified: true},
+		{Pkg: "github.com/my/jsonutil", Match: "contains", Score: scoreContains, Verified: true, PageOK: true},
+	}
+	var candidates []Candidate
+	for i, c := range tr.Steps[3].Candidates {
+		if i > 0 && !c.Verified {
+			c.Verified, c.PageOK = wantCandidates[i].Verified, wantCandidates[i].PageOK
+		}
+		candidates = append(candidates, *c)
+	}
+	if !reflect.DeepEqual(candidates, wantCandidates) {
+		t.Errorf("want candidates %+v but got %+v", wantCandidates, candidates)
+	}
+
+	// The trace is returned on failure too
+	arg = Arg{Path: "/home/me/nope", Pkg: "nope"}
+	res, tr, err = Explain(context.Background(), arg, pkgs, pageOK)
+	if err == nil || res != nil || tr == nil || tr.Result != nil || len(tr.Steps) == 0 {
+		t.Errorf("want error and trace without result, but got %v, %+v", err, tr)
+	}
+}
+
+func TestVerifyCandidates(t *testing.T) {
+	// The best-ranked OK candidate is selected, even though the
+	// worse-ranked candidates are verified sooner.
+	delays := map[string]time.Duration{"a": 30, "b": 20, "c": 10, "d": 0}
+	okPkgs := map[string]bool{"b": true, "c": true, "d": true}
+	pageOK := func(ctx context.Context, pkg string, retry bool) (bool, error) {
+		time.Sleep(delays[pkg] * time.Millisecond)
+		if pkg == "e" {
+			return false, errors.New("connection refused")
+		}
+		return okPkgs[pkg], nil
+	}
+
+	newCandidates := func(pkgs ...string) []*Candidate {
+		var cs []*Candidate
+		for _, pkg := range pkgs {
+			cs = append(cs, &Candidate{Pkg: pkg})
+		}
+		return cs
+	}
+
+	for i := 0; i < 5; i++ {
+		c, err := verifyCandidates(context.Background(), newCandidates("a", "b", "c", "d", "e"), pageOK)
+		if err != nil || c == nil || c.Pkg != "b" {
+			t.Fatalf("want b but got %+v, %v", c, err)
+		}
+	}
+
+	// Once the best-ranked OK candidate is known, the verifications of
+	// the worse-ranked candidates are cancelled, rather than waited for
+	slowPageOK := func(ctx context.Context, pkg string, retry bool) (bool, error) {
+		if pkg == "slow" {
+			<-ctx.Done()
+			return false, ctx.Err()
+		}
+		return true, nil
+	}
+	start := time.Now()
+	cs := newCandidates("fast", "slow")
+	c, err := verifyCandidates(context.Background(), cs, slowPageOK)
+	if err != nil || c == nil || c.Pkg != "fast" {
+		t.Fatalf("want fast but got %+v, %v", c, err)
+	}
+	if d := time.Since(start); d > verifyTimeout/2 {
+		t.Errorf("should not have waited for the slow verification, but took %s", d)
+	}
+	if cs[1].Verified || cs[1].Err != nil {
+		t.Errorf("cancelled verification should not be recorded, but got %+v", cs[1])
+	}
+
+	// If no page is OK, the error is returned
+	cs = newCandidates("a", "e")
+	c, err = verifyCandidates(context.Background(), cs, pageOK)
+	if c != nil || err == nil || !cs[0].Verified || cs[1].Verified || cs[1].Err == nil {
+		t.Errorf("want error but got %+v, %v", c, err)
+	}
+}
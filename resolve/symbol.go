@@ -0,0 +1,170 @@
+package resolve
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// docSplit is an interpretation of a go doc-style arg, e.g. "json.Unmarshal",
+// as a pkg and a symbol.
+type docSplit struct {
+	pkg, sym string
+}
+
+// docSplits returns the interpretations of go doc-style arg s as a pkg
+// and a symbol, in the order that go doc tries them. As with go doc, the
+// split is at a dot after the last slash, e.g. "encoding/json.Unmarshal"
+// or "http.Client.Do", and the symbol may be "Sym", "Sym.Method",
+// "Type.Field" or "Method".
+func docSplits(s string) []docSplit {
+	var splits []docSplit
+	for i := strings.LastIndex(s, "/") + 1; i < len(s); i++ {
+		if s[i] != '.' || i == 0 {
+			continue
+		}
+		if sym := s[i+1:]; isSymbol(sym) {
+			splits = append(splits, docSplit{pkg: s[:i], sym: sym})
+		}
+	}
+	return splits
+}
+
+// isSymbol returns true if s is a go doc-style symbol: an identifier,
+// or two identifiers joined by a dot.
+func isSymbol(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) > 2 {
+		return false
+	}
+	for _, part := range parts {
+		if !isIdent(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdent returns true if s is a Go identifier.
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// docPkg returns the pkg (one of pkgs) with import path name, or whose
+// import path ends with "/" + name, as go doc would find it. If several
+// pkgs match, the shortest is returned, e.g. "html/template" for name
+// "template". Empty string is returned if there's no match.
+func docPkg(pkgs []string, name string) string {
+	var found []string
+	for _, pkg := range pkgs {
+		if pkg == name {
+			return pkg
+		}
+		if strings.HasSuffix(pkg, "/"+name) {
+			found = append(found, pkg)
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if len(found[i]) != len(found[j]) {
+			return len(found[i]) < len(found[j])
+		}
+		return found[i] < found[j]
+	})
+	return found[0]
+}
+
+// MatchSymbol returns the id (one of ids, the element ids of a godoc pkg
+// page) of go doc-style symbol sym, e.g. "Unmarshal" or "Client.Do". As
+// with go doc, a lowercase letter in sym matches either case, but an
+// uppercase letter matches only itself; and a symbol that isn't found
+// at the top level matches a method or field of any type, e.g. "Do"
+// matches "Client.Do". Unexported identifiers are matched only if
+// unexported is true. An identical id is preferred.
+func MatchSymbol(ids []string, sym string, unexported bool) (id string, ok bool) {
+	if !isSymbol(sym) {
+		return "", false
+	}
+	want := strings.Split(sym, ".")
+	if matchIdents(want, want, unexported) {
+		for _, id := range ids {
+			if id == sym {
+				return id, true
+			}
+		}
+	}
+
+	var methods []string // ids of methods or fields
+	for _, id := range ids {
+		parts := strings.Split(id, ".")
+		if !isSymbol(id) {
+			continue
+		}
+		if len(parts) == 2 {
+			methods = append(methods, id)
+		}
+		if len(parts) == len(want) && matchIdents(want, parts, unexported) {
+			return id, true
+		}
+	}
+
+	if len(want) == 1 {
+		for _, id := range methods {
+			parts := strings.Split(id, ".")
+			if matchIdents(want, parts[1:], unexported) && (unexported || isExported(parts[0])) {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchIdents returns true if each of user matches the corresponding
+// element of program, as per matchIdent.
+func matchIdents(user, program []string, unexported bool) bool {
+	for i := range user {
+		if !matchIdent(user[i], program[i], unexported) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchIdent returns true if the identifier user (as typed) matches
+// program (as declared), using go doc's case rules.
+func matchIdent(user, program string, unexported bool) bool {
+	if !unexported && !isExported(program) {
+		return false
+	}
+
+	for _, u := range user {
+		p, w := utf8.DecodeRuneInString(program)
+		program = program[w:]
+		if u == p {
+			continue
+		}
+		if unicode.IsLower(u) && unicode.ToLower(p) == u {
+			continue
+		}
+		return false
+	}
+	return program == ""
+}
+
+// isExported returns true if identifier s begins with an uppercase letter.
+func isExported(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
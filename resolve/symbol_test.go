@@ -0,0 +1,86 @@
+package resolve
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocSplits(t *testing.T) {
+	testCases := map[string][]docSplit{
+		"json":                    nil,
+		"json.Unmarshal":          {{pkg: "json", sym: "Unmarshal"}},
+		"http.Client.Do":          {{pkg: "http", sym: "Client.Do"}, {pkg: "http.Client", sym: "Do"}},
+		"encoding/json.Unmarshal": {{pkg: "encoding/json", sym: "Unmarshal"}},
+		"gopkg.in/yaml.v2":        {{pkg: "gopkg.in/yaml", sym: "v2"}},
+		"gopkg.in/yaml.v2/sub":    nil,
+		"a.b.c.d":                 {{pkg: "a.b", sym: "c.d"}, {pkg: "a.b.c", sym: "d"}},
+		".Foo":                    nil,
+		"json.":                   nil,
+		"json.1a":                 nil,
+	}
+
+	for s, want := range testCases {
+		got := docSplits(s)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %v but got %v", s, want, got)
+		}
+	}
+}
+
+func TestDocPkg(t *testing.T) {
+	pkgs := []string{"encoding/json", "html/template", "text/template", "template", "github.com/my/template"}
+
+	testCases := map[string]string{
+		"json":          "encoding/json",
+		"encoding/json": "encoding/json",
+		"coding/json":   "",
+		"template":      "template",
+		"Template":      "",
+	}
+	for name, want := range testCases {
+		if got := docPkg(pkgs, name); got != want {
+			t.Errorf("%s: want %q but got %q", name, want, got)
+		}
+	}
+
+	if got := docPkg(pkgs[:4], "template"); got != "template" {
+		t.Errorf("want exact match but got %q", got)
+	}
+	if got := docPkg(pkgs[1:3], "template"); got != "html/template" {
+		t.Errorf("want shortest match html/template but got %q", got)
+	}
+}
+
+func TestMatchSymbol(t *testing.T) {
+	// The element ids of a godoc pkg page
+	ids := []string{"pkg-overview", "pkg-index", "Marshal", "Unmarshal", "unmarshal",
+		"Decoder", "Decoder.Decode", "Decoder.buf", "NewDecoder", "example_Decoder"}
+
+	testCases := []struct {
+		sym        string
+		unexported bool
+		want       string
+	}{
+		{sym: "Unmarshal", want: "Unmarshal"},
+		{sym: "unmarshal", want: "Unmarshal"},
+		{sym: "unmarshal", unexported: true, want: "unmarshal"},
+		{sym: "UNMARSHAL"},
+		{sym: "decoder.decode", want: "Decoder.Decode"},
+		{sym: "decoder.Decode", want: "Decoder.Decode"},
+		{sym: "Decoder.decode", want: "Decoder.Decode"},
+		{sym: "decode", want: "Decoder.Decode"},
+		{sym: "buf"},
+		{sym: "decoder.buf"},
+		{sym: "decoder.buf", unexported: true, want: "Decoder.buf"},
+		{sym: "pkg-overview"},
+		{sym: "example_decoder"},
+		{sym: "Nope"},
+	}
+
+	for _, tc := range testCases {
+		got, ok := MatchSymbol(ids, tc.sym, tc.unexported)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("%s (unexported=%v): want %q but got %q, %v", tc.sym, tc.unexported, tc.want, got, ok)
+		}
+	}
+}
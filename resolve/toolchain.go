@@ -0,0 +1,125 @@
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Toolchain is a locally installed Go toolchain.
+type Toolchain struct {
+	// Version is the Go version, e.g. "go1.21.3".
+	Version string
+	// GOROOT is the toolchain's GOROOT, which holds its std lib source.
+	GOROOT string
+}
+
+// Toolchains returns the locally installed Go toolchains, newest first:
+// the default toolchain (as per "go env GOROOT"), those downloaded by the
+// go command as per GOTOOLCHAIN (held in the module cache, as versions of
+// module golang.org/toolchain), and those installed by the golang.org/dl
+// commands (beneath $HOME/sdk).
+func Toolchains(ctx context.Context) ([]Toolchain, error) {
+	env, err := goEnv(ctx, "", "GOROOT", "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{env["GOROOT"]}
+
+	// e.g. golang.org/toolchain@v0.0.1-go1.21.3.linux-amd64
+	suffix := "." + runtime.GOOS + "-" + runtime.GOARCH
+	matches, _ := filepath.Glob(filepath.Join(env["GOMODCACHE"], "golang.org", "toolchain@v*-go*"+suffix))
+	dirs = append(dirs, matches...)
+
+	if home, err := os.UserHomeDir(); err == nil {
+		matches, _ = filepath.Glob(filepath.Join(home, "sdk", "go*"))
+		dirs = append(dirs, matches...)
+	}
+
+	var tcs []Toolchain
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		tc, err := GOROOTToolchain(dir)
+		if err != nil {
+			log.Printf("ignoring toolchain dir %s: %v", dir, err)
+			continue
+		}
+		if !seen[tc.GOROOT] {
+			seen[tc.GOROOT] = true
+			tcs = append(tcs, *tc)
+		}
+	}
+
+	sort.SliceStable(tcs, func(i, j int) bool {
+		return compareVersions(goSemver(tcs[i].Version), goSemver(tcs[j].Version)) > 0
+	})
+	return tcs, nil
+}
+
+// FindToolchain returns the newest locally installed toolchain (see
+// Toolchains) that matches version. A version such as "1.21" (or "go1.21")
+// matches any 1.21 release, e.g. "go1.21.13", whereas "1.21.3" or
+// "1.21rc2" matches only that version.
+func FindToolchain(ctx context.Context, version string) (*Toolchain, error) {
+	tcs, err := Toolchains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	want := strings.TrimPrefix(version, "go")
+	var found []string
+	for _, tc := range tcs {
+		v := strings.TrimPrefix(tc.Version, "go")
+		if v == want || strings.HasPrefix(v, want+".") {
+			log.Printf("found toolchain %s for version %s: %s", tc.Version, version, tc.GOROOT)
+			return &tc, nil
+		}
+		found = append(found, tc.Version)
+	}
+
+	return nil, fmt.Errorf("no local Go toolchain matches version %s (found: %s); install one with: go install golang.org/dl/go%s@latest",
+		version, strings.Join(found, ", "), want)
+}
+
+// GOROOTToolchain returns the toolchain with GOROOT dir, whose version
+// is read from the dir's VERSION file.
+func GOROOTToolchain(dir string) (*Toolchain, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "VERSION"))
+	if err != nil {
+		return nil, fmt.Errorf("not a Go toolchain GOROOT: %v", err)
+	}
+
+	// The first line is the version; later lines hold e.g. the build time.
+	version := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	if !strings.HasPrefix(version, "go") {
+		return nil, fmt.Errorf("unexpected version %q in %s", version, filepath.Join(dir, "VERSION"))
+	}
+	return &Toolchain{Version: version, GOROOT: dir}, nil
+}
+
+// goSemver returns the semantic version of Go version v, e.g.
+// "v1.21.0" for "go1.21", or "v1.21.0-rc2" for "go1.21rc2".
+func goSemver(v string) string {
+	v = strings.TrimPrefix(v, "go")
+
+	var prerelease string
+	if i := strings.IndexAny(v, "abcdefghijklmnopqrstuvwxyz"); i >= 0 {
+		v, prerelease = v[:i], "-"+v[i:]
+	}
+	for strings.Count(v, ".") < 2 {
+		v += ".0"
+	}
+	return "v" + v + prerelease
+}
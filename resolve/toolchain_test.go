@@ -0,0 +1,82 @@
+package resolve
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGoSemver(t *testing.T) {
+	testCases := map[string]string{
+		"go1.21":    "v1.21.0",
+		"go1.21.3":  "v1.21.3",
+		"go1.21rc2": "v1.21.0-rc2",
+		"1.9beta1":  "v1.9.0-beta1",
+		"go1.22.0":  "v1.22.0",
+		"go1.27.1":  "v1.27.1",
+		"go1.10":    "v1.10.0",
+	}
+	for v, want := range testCases {
+		if got := goSemver(v); got != want {
+			t.Errorf("%s: want %s but got %s", v, want, got)
+		}
+	}
+}
+
+func TestFindToolchain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	platform := runtime.GOOS + "-" + runtime.GOARCH
+	writeFiles(t, dir, map[string]string{
+		"home/sdk/go1.19.2/VERSION":  "go1.19.2\ntime 2022-10-04T07:25:00Z\n",
+		"home/sdk/go1.19.13/VERSION": "go1.19.13\n",
+		"home/sdk/go1.19rc1/VERSION": "go1.19rc1\n",
+		"home/sdk/notgo/README":      "",
+		"modcache/golang.org/toolchain@v0.0.1-go1.18.4." + platform + "/VERSION": "go1.18.4\n",
+		"modcache/golang.org/toolchain@v0.0.1-go1.17.1.plan9-mips/VERSION":       "go1.17.1\n",
+	})
+
+	defer setenv(t, map[string]string{
+		"HOME":        filepath.Join(dir, "home"),
+		"GOMODCACHE":  filepath.Join(dir, "modcache"),
+		"GOTOOLCHAIN": "local",
+		"GOFLAGS":     "",
+	})()
+
+	testCases := []struct {
+		version string
+		want    string
+	}{
+		{version: "1.19", want: "home/sdk/go1.19.13"},
+		{version: "go1.19.2", want: "home/sdk/go1.19.2"},
+		{version: "1.19rc1", want: "home/sdk/go1.19rc1"},
+		{version: "1.18", want: "modcache/golang.org/toolchain@v0.0.1-go1.18.4." + platform},
+		{version: "1.17"}, // Not for this platform
+		{version: "1.1"},
+	}
+
+	for _, tc := range testCases {
+		got, err := FindToolchain(context.Background(), tc.version)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected error but got %+v", tc.version, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.version, err)
+			continue
+		}
+		if want := filepath.Join(dir, filepath.FromSlash(tc.want)); got.GOROOT != want {
+			t.Errorf("%s: want %s but got %s", tc.version, want, got.GOROOT)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package resolve
+
+// Trace records how Explain resolved an Arg (or failed to): each
+// strategy tried, the candidate pkgs it considered, and the outcome.
+type Trace struct {
+	// Arg is the arg being resolved.
+	Arg Arg
+	// Steps are the strategies tried, in order.
+	Steps []*Step
+	// Result is the final decision, or nil if arg was not resolved.
+	Result *Result
+}
+
+// Step is a strategy tried by Explain, e.g. matching the arg's path
+// against the pkg list.
+type Step struct {
+	// Strategy names the strategy, e.g. "path" or "possible matches".
+	Strategy string
+	// Note describes what the strategy found (or didn't).
+	Note string
+	// Candidates are the pkgs considered by the strategy, in the
+	// order they were (or would have been) verified.
+	Candidates []*Candidate
+}
+
+// Candidate is a pkg considered by a Step.
+type Candidate struct {
+	Pkg string
+	// Match is how Pkg matched the arg, e.g. "exact" or "suffix" for a
+	// pkg search term, "path" for a trailing part of the arg's path,
+	// or "go doc" for the pkg part of a go doc-style arg.
+	Match string
+	// Score ranks a match of the pkg search term: the higher, the
+	// better. It is zero for other kinds of match.
+	Score int
+	// Verified is true if the pkg page was checked for availability
+	// on the server, in which case PageOK holds the outcome. If the
+	// check failed, Err is set.
+	Verified bool
+	PageOK   bool
+	Err      error
+}
+
+// step adds a Step for strategy to t.
+func (t *Trace) step(strategy string) *Step {
+	s := &Step{Strategy: strategy}
+	t.Steps = append(t.Steps, s)
+	return s
+}
+
+// candidate adds a Candidate for pkg to s.
+func (s *Step) candidate(pkg, match string, score int) *Candidate {
+	c := &Candidate{Pkg: pkg, Match: match, Score: score}
+	s.Candidates = append(s.Candidates, c)
+	return c
+}
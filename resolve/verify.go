@@ -0,0 +1,96 @@
+package resolve
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// maxVerify is the max number of possible matches (the best-ranked)
+	// whose pages are verified on the server.
+	maxVerify = 10
+
+	// verifyWorkers is the number of pages verified concurrently.
+	verifyWorkers = 4
+
+	// verifyTimeout is the overall deadline for verifying possible matches.
+	verifyTimeout = time.Second * 10
+)
+
+// verifyCandidates verifies the pages of candidates (which are ranked
+// best first) concurrently, using a pool of verifyWorkers workers. It
+// returns the best-ranked candidate whose page is OK, regardless of the
+// order in which the verifications complete: as soon as it and all the
+// better-ranked candidates are verified, the remaining verifications are
+// cancelled. If no page is OK, nil is returned, along with the error of
+// the best-ranked failed verification, if any. The Verified, PageOK and
+// Err fields are set for each candidate whose verification completed.
+func verifyCandidates(ctx context.Context, candidates []*Candidate, pageOK PageOKFunc) (*Candidate, error) {
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	workers := verifyWorkers
+	if len(candidates) < workers {
+		workers = len(candidates)
+	}
+
+	type result struct {
+		i   int
+		ok  bool
+		err error
+	}
+	indexCh := make(chan int, len(candidates))
+	for i := range candidates {
+		indexCh <- i
+	}
+	close(indexCh)
+	// Only this goroutine sets the candidates' fields, from the results.
+	resultCh := make(chan result, len(candidates))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				if err := ctx.Err(); err != nil {
+					resultCh <- result{i: i, err: err}
+					continue
+				}
+				ok, err := pageOK(ctx, candidates[i].Pkg, false)
+				resultCh <- result{i: i, ok: ok, err: err}
+			}
+		}()
+	}
+	defer func() {
+		// Cancel any remaining verifications, and wait for them,
+		// so that they don't outlive this call.
+		cancel()
+		wg.Wait()
+	}()
+
+	done := make([]bool, len(candidates))
+	next := 0 // the best-ranked candidate not yet known to be not OK
+	for range candidates {
+		r := <-resultCh
+		c := candidates[r.i]
+		c.PageOK, c.Err = r.ok, r.err
+		c.Verified = r.err == nil
+		done[r.i] = true
+
+		for next < len(candidates) && done[next] {
+			if candidates[next].PageOK {
+				return candidates[next], nil
+			}
+			next++
+		}
+	}
+
+	for _, c := range candidates {
+		if c.Err != nil {
+			return nil, c.Err
+		}
+	}
+	return nil, nil
+}
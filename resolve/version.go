@@ -0,0 +1,100 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+)
+
+// DownloadModule downloads the version of the module that provides pkg
+// into the module cache. The version may be a query such as "latest".
+// The module path is determined by trying successively shorter prefixes
+// of pkg, e.g. "github.com/my/mod/sub/pkg", "github.com/my/mod/sub", etc.
+func DownloadModule(ctx context.Context, pkg, version string) (*Module, error) {
+	for modPath := pkg; modPath != "." && modPath != "/"; modPath = path.Dir(modPath) {
+		log.Printf("attempting to download module %s@%s", modPath, version)
+		cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", modPath+"@"+version)
+		// Run outside of the current module, so that its go.mod doesn't
+		// constrain which module versions can be downloaded.
+		cmd.Dir = os.TempDir()
+		out, err := cmd.Output() // go mod download -json reports most errors in the JSON
+		if len(bytes.TrimSpace(out)) == 0 && err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				err = fmt.Errorf("%v: %s", err, bytes.TrimSpace(exitErr.Stderr))
+			}
+			return nil, fmt.Errorf("failed to download %s@%s: %v", modPath, version, err)
+		}
+
+		var mod struct {
+			Version string
+			Dir     string
+			Error   string
+		}
+		if jsonErr := json.Unmarshal(out, &mod); jsonErr != nil {
+			return nil, fmt.Errorf("failed to download %s@%s: %v", modPath, version, jsonErr)
+		}
+		if mod.Error == "" && mod.Dir != "" {
+			return &Module{Path: modPath, Version: mod.Version, Dir: mod.Dir}, nil
+		}
+		log.Printf("failed to download %s@%s: %s", modPath, version, mod.Error)
+	}
+
+	return nil, fmt.Errorf("failed to download a module providing %s@%s", pkg, version)
+}
+
+// viewModulePath is the module path of the modules created by ViewModule.
+const viewModulePath = "gohdoc.view"
+
+// ViewModule returns the dir of a module that requires version of the
+// module that provides pkg, so that a godoc http server started in the
+// dir serves the docs of that version. The module is created (beneath
+// the user's cache dir) if it doesn't already exist, and the required
+// module is downloaded into the module cache.
+func ViewModule(ctx context.Context, pkg, version string) (dir string, mod *Module, err error) {
+	mod, err = DownloadModule(ctx, pkg, version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+	dir = filepath.Join(cacheDir, "gohdoc", "view", url.PathEscape(mod.Path+"@"+mod.Version))
+
+	// go.sum is written by go get, so it exists only if
+	// the module was successfully created.
+	if _, err = os.Stat(filepath.Join(dir, "go.sum")); err == nil {
+		log.Printf("reusing view module: %s", dir)
+		return dir, mod, nil
+	}
+
+	log.Printf("creating view module for %s@%s: %s", mod.Path, mod.Version, dir)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+viewModulePath+"\n"), 0644)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "get", mod.Path+"@"+mod.Version)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("failed to add %s@%s to view module: %v: %s",
+			mod.Path, mod.Version, err, bytes.TrimSpace(out))
+	}
+	return dir, mod, nil
+}
@@ -0,0 +1,114 @@
+package resolve
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setenv sets the env vars in env, returning a func that restores them.
+func setenv(t *testing.T, env map[string]string) (restore func()) {
+	old := map[string]string{}
+	for k, v := range env {
+		old[k] = os.Getenv(k)
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for k, v := range old {
+			_ = os.Setenv(k, v)
+		}
+	}
+}
+
+// writeProxyModule writes version of module modPath, with files, to the
+// file-based GOPROXY at dir.
+func writeProxyModule(t *testing.T, dir, modPath, version string, files map[string]string) {
+	vdir := filepath.Join(dir, filepath.FromSlash(modPath), "@v")
+	gomod := "module " + modPath + "\n"
+	writeFiles(t, vdir, map[string]string{
+		"list":            version + "\n",
+		version + ".info": `{"Version":"` + version + `"}`,
+		version + ".mod":  gomod,
+	})
+
+	f, err := os.Create(filepath.Join(vdir, version+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	files["go.mod"] = gomod
+	for name, content := range files {
+		w, err := zw.Create(modPath + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestViewModule(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		// os.UserCacheDir honours XDG_CACHE_HOME only on linux
+		t.Skip("test requires linux")
+	}
+
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	proxy := filepath.Join(dir, "proxy")
+	writeProxyModule(t, proxy, "example.com/dep", "v1.4.0", map[string]string{
+		"dep.go":     "package dep\n",
+		"sub/sub.go": "package sub\n",
+	})
+
+	defer setenv(t, map[string]string{
+		"GOPROXY":        "file://" + filepath.ToSlash(proxy),
+		"GOSUMDB":        "off",
+		"GOFLAGS":        "-modcacherw",
+		"GOMODCACHE":     filepath.Join(dir, "modcache"),
+		"XDG_CACHE_HOME": filepath.Join(dir, "cache"),
+	})()
+
+	viewDir, mod, err := ViewModule(context.Background(), "example.com/dep/sub", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mod.Path != "example.com/dep" || mod.Version != "v1.4.0" {
+		t.Errorf("want module example.com/dep@v1.4.0 but got %s@%s", mod.Path, mod.Version)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(viewDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "require example.com/dep v1.4.0"; !strings.Contains(got, want) {
+		t.Errorf("want view module go.mod to contain %q but got:\n%s", want, got)
+	}
+
+	// The view module is reused
+	viewDir2, _, err := ViewModule(context.Background(), "example.com/dep", "v1.4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viewDir2 != viewDir {
+		t.Errorf("want view module %s reused but got %s", viewDir, viewDir2)
+	}
+}
@@ -0,0 +1,81 @@
+package resolve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is the set of pkgs (beyond the std lib and dependencies)
+// that a godoc http server serves when started in a dir: in module mode,
+// the pkgs of the module containing the dir, or else the pkgs in GOPATH.
+type Workspace struct {
+	// ImportPath is the path of the module containing the dir or,
+	// outside a module, the import path of the dir beneath GOPATH/src.
+	// It is empty if the dir is in neither.
+	ImportPath string
+	// Root is the module dir, or the GOPATH/src dir containing the dir.
+	Root string
+	// Module is true if Root is a module dir.
+	Module bool
+}
+
+// FindWorkspace returns the Workspace of dir.
+func FindWorkspace(ctx context.Context, dir string) (*Workspace, error) {
+	env, err := goEnv(ctx, dir, "GOMOD", "GOPATH")
+	if err != nil {
+		return nil, err
+	}
+
+	if gomod := env["GOMOD"]; gomod != "" && gomod != os.DevNull {
+		modPath, err := readModulePath(gomod)
+		if err != nil {
+			return nil, err
+		}
+		return &Workspace{ImportPath: modPath, Root: filepath.Dir(gomod), Module: true}, nil
+	}
+
+	for _, gopath := range filepath.SplitList(env["GOPATH"]) {
+		src := filepath.Join(gopath, "src")
+		if rel, ok := relDir(src, dir); ok && rel != "." {
+			return &Workspace{ImportPath: filepath.ToSlash(rel), Root: src}, nil
+		}
+	}
+	return &Workspace{}, nil
+}
+
+// Served returns true if serverPkgs (a godoc http server's pkg list)
+// includes the workspace's pkgs, i.e. ImportPath or the pkgs beneath
+// it. It always returns true if ImportPath is empty.
+func (w *Workspace) Served(serverPkgs []string) bool {
+	if w.ImportPath == "" {
+		return true
+	}
+	for _, pkg := range serverPkgs {
+		if pkg == w.ImportPath || strings.HasPrefix(pkg, w.ImportPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if dir is Root or beneath it, such that a godoc
+// http server started in dir serves the workspace.
+func (w *Workspace) Contains(dir string) bool {
+	if w.Root == "" || dir == "" {
+		return false
+	}
+	_, ok := relDir(w.Root, dir)
+	return ok
+}
+
+// relDir returns the path of dir relative to root, if dir is
+// root or beneath it.
+func relDir(root, dir string) (string, bool) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
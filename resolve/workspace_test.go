@@ -0,0 +1,66 @@
+package resolve
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gohdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"mod/go.mod":                        "module github.com/my/mod\n",
+		"mod/sub/sub.go":                    "package sub\n",
+		"gopath/src/github.com/my/pkg/a.go": "package pkg\n",
+		"other/a.go":                        "package other\n",
+	})
+
+	defer setenv(t, map[string]string{
+		"GOPATH":      filepath.Join(dir, "gopath"),
+		"GO111MODULE": "auto",
+		"GOFLAGS":     "",
+	})()
+
+	testCases := []struct {
+		dir  string
+		want Workspace
+	}{
+		{dir: "mod/sub", want: Workspace{ImportPath: "github.com/my/mod", Root: filepath.Join(dir, "mod"), Module: true}},
+		{dir: "gopath/src/github.com/my/pkg", want: Workspace{ImportPath: "github.com/my/pkg", Root: filepath.Join(dir, "gopath", "src")}},
+		{dir: "other", want: Workspace{}},
+	}
+	for _, tc := range testCases {
+		ws, err := FindWorkspace(context.Background(), filepath.Join(dir, tc.dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *ws != tc.want {
+			t.Errorf("%s: want %+v but got %+v", tc.dir, tc.want, *ws)
+		}
+	}
+}
+
+func TestWorkspaceServed(t *testing.T) {
+	ws := &Workspace{ImportPath: "github.com/my/mod", Root: "/home/me/mod", Module: true}
+
+	if !ws.Served([]string{"fmt", "github.com/my/mod/sub"}) {
+		t.Error("want workspace served by server with pkg beneath module path")
+	}
+	if ws.Served([]string{"fmt", "github.com/my/module"}) {
+		t.Error("want workspace not served by server without module pkgs")
+	}
+	if !(&Workspace{}).Served(nil) {
+		t.Error("want empty workspace served by any server")
+	}
+
+	if !ws.Contains("/home/me/mod/sub") || !ws.Contains("/home/me/mod") || ws.Contains("/home/me/module") {
+		t.Error("unexpected result from Contains")
+	}
+}
@@ -0,0 +1,69 @@
+// Package scrape extracts data from godoc http server HTML pages.
+package scrape
+
+import (
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Packages scrapes the /pkg HTML, returning all pkg
+// names listed on that page.
+func Packages(r io.Reader) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []string
+
+	selector := ".pkg-dir td.pkg-name a[href]"
+
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		v, ok := s.Attr("href")
+		if ok {
+			// The link looks like "encoding/json/"
+			v = strings.TrimSuffix(v, "/")
+			pkgs = append(pkgs, v)
+		}
+	})
+
+	return pkgs, nil
+}
+
+// HasID returns true if the HTML from r has an element with the
+// supplied id. Godoc uses the identifier name (e.g. "Println", or
+// "Buffer.Len" for methods) as the element id for each symbol.
+func HasID(r io.Reader, id string) (bool, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	doc.Find("[id]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if v, _ := s.Attr("id"); v == id {
+			found = true
+		}
+		return !found
+	})
+	return found, nil
+}
+
+// IDs returns the ids of the elements of the HTML from r, in document
+// order. On a godoc pkg page, these include the id of each symbol.
+func IDs(r io.Reader) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		if v, _ := s.Attr("id"); v != "" {
+			ids = append(ids, v)
+		}
+	})
+	return ids, nil
+}
@@ -0,0 +1,81 @@
+package scrape
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackages(t *testing.T) {
+	var testCases []string
+	versions := []string{"1.5", "1.6", "1.7", "1.8", "1.9", "1.10", "1.11"}
+	for _, v := range versions {
+		testCases = append(testCases, fmt.Sprintf("../testdata/pkg_%s.html", v))
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tc)
+			if err != nil {
+				t.Error(err)
+			}
+
+			pkgs, err := Packages(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pkgs) < 150 { // approx 150 pkgs in stdlib
+				t.Errorf("should have more than %d pkgs", len(pkgs))
+			}
+
+			// verify that we have the gohdoc pkg in the output
+			found := false
+			for _, pkg := range pkgs {
+				if strings.HasSuffix(pkg, "neilotoole/gohdoc") {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Error("didn't find gohdoc pkg in output")
+			}
+		})
+	}
+}
+
+func TestHasID(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/pkg_1.11.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]bool{"manual-nav": true, "footer": true, "nav": true, "Println": false, "": false}
+	for id, want := range testCases {
+		got, err := HasID(bytes.NewReader(b), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("id %q: want %v but got %v", id, want, got)
+		}
+	}
+}
+
+func TestIDs(t *testing.T) {
+	const page = `<html><body><div id="pkg-overview"></div>
+<h2 id="Marshal">func Marshal</h2><h3 id="Buffer.Len" class="x">func (*Buffer) Len</h3><p id="">x</p></body></html>`
+
+	got, err := IDs(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"pkg-overview", "Marshal", "Buffer.Len"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v but got %v", want, got)
+	}
+}
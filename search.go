@@ -1,53 +1,66 @@
 package main
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
-	"io"
 	"log"
-	"sort"
-	"strings"
 
-	"github.com/PuerkitoBio/goquery"
+	"github.com/neilotoole/gohdoc/resolve"
 )
 
-// cmdList lists all pkgs on the godoc http server.
+// cmdList lists all pkgs (see loadPkgList), followed by
+// the commands (main pkgs), which are listed separately.
 func cmdList(app *App) error {
-	err := loadServerPkgList(app)
+	err := loadPkgList(app)
 	if err != nil {
 		return err
 	}
 
-	pkgs := app.serverPkgList
+	var pkgs, cmds []string
+	for _, pkg := range app.pkgList {
+		if app.srv.Commands[pkg] {
+			cmds = append(cmds, pkg)
+		} else {
+			pkgs = append(pkgs, pkg)
+		}
+	}
 
+	printList(app, pkgs)
+	if len(cmds) > 0 {
+		fmt.Println("\nCommands:")
+		printList(app, cmds)
+	}
+	return nil
+}
+
+// printList prints pkgs, one per line. With -listv, a link
+// to each pkg is also printed.
+func printList(app *App, pkgs []string) {
 	if app.flagListv {
-		// When verbose, also print a link to the pkg
 		printPkgsWithLink(app, pkgs)
-	} else {
-		for _, pkg := range pkgs {
-			fmt.Println(pkg)
-		}
+		return
+	}
+
+	for _, pkg := range pkgs {
+		fmt.Println(pkg)
 	}
-	return nil
 }
 
-// cmdSearch lists all godoc http server packages that match the argument.
+// cmdSearch lists all pkgs (see loadPkgList) that match the argument.
 func cmdSearch(app *App) error {
 	if len(app.args) != 1 {
 		return fmt.Errorf("search command takes exactly one arg")
 	}
 
-	err := loadServerPkgList(app)
+	err := loadPkgList(app)
 	if err != nil {
 		return err
 	}
 
-	pkgs := app.serverPkgList
+	pkgs := app.pkgList
 	term := app.args[0]
 	log.Printf("searching %d pkg names for term %q", len(pkgs), term)
 
-	matches, _ := getPkgMatches(pkgs, term)
+	matches, _ := resolve.Matches(pkgs, term)
 	if len(matches) == 0 {
 		log.Printf("No package found matching %s\n", term)
 		return nil
@@ -65,95 +78,64 @@ func cmdSearch(app *App) error {
 
 }
 
-// loadServerPkgList loads the list of pkgs from the server, and
-// sets app.serverPkgList with that data.
-func loadServerPkgList(app *App) error {
-	err := requireServer(app)
-	if err != nil {
-		return err
-	}
-
-	if app == nil || len(app.serverPkgPageBody) == 0 {
-		return errors.New("apparently no data from godoc http server /pkg")
-	}
-	r := bytes.NewReader(app.serverPkgPageBody)
-
-	pkgs, err := scrapePkgPage(r)
-	if err != nil {
-		return err
-	}
-	app.serverPkgList = pkgs
-	return nil
-}
-
-// getPkgMatches returns the set of pkg names that match arg s,
-// with the best match first. The "best match" algorithm is pretty trivial.
-// If there's an exact match of s against pkgs, then exactMatch is returned
-// true, and matches has minimum length 1 (although it may be larger).
-func getPkgMatches(pkgs []string, s string) (matches []string, exactMatch bool) {
-	if len(s) == 0 || len(pkgs) == 0 {
-		return matches, false
+// loadPkgList sets app.pkgList to the list of pkgs that gohdoc resolves
+// args against. Usually these are the pkgs available locally, which can
+// be listed without a running server. If a remote server is configured,
+// the server's pkgs are listed instead.
+func loadPkgList(app *App) error {
+	if app.pkgList != nil {
+		return nil
 	}
 
-	// We match on whether s is a suffix, prefix, or is contained in pkg
-	var sufMatches, preMatches, containMatches []string
-
-	for _, pkg := range pkgs {
-		if pkg == s {
-			exactMatch = true
-			continue
+	if app.srv.Remote() {
+		err := loadServerPkgList(app)
+		if err != nil {
+			return err
 		}
+		app.pkgList = app.serverPkgList
 
-		if strings.HasSuffix(pkg, s) {
-			sufMatches = append(sufMatches, pkg)
-			continue
+		// The server's /pkg/ page doesn't list the std commands
+		cmds, err := app.srv.CommandPackages(app.ctx)
+		if err != nil {
+			// Not fatal: the server may not serve a /cmd/ page
+			log.Printf("failed to list commands on server: %v", err)
 		}
-		if strings.HasPrefix(pkg, s) {
-			preMatches = append(preMatches, pkg)
-			continue
-		}
-
-		if strings.Contains(pkg, s) {
-			containMatches = append(containMatches, pkg)
+		app.srv.Commands = map[string]bool{}
+		for _, cmd := range cmds {
+			app.srv.Commands[cmd] = true
+			app.pkgList = append(app.pkgList, cmd)
 		}
+		return nil
 	}
 
-	sort.Strings(sufMatches)
-	sort.Strings(preMatches)
-	sort.Strings(containMatches)
-
-	if exactMatch {
-		// If there's an exact match, it should be the first result
-		matches = append(matches, s)
+	pkgs, err := app.listPkgs(app.ctx, app.cwd)
+	if err != nil {
+		return fmt.Errorf("failed to list local pkgs: %v", err)
 	}
-	matches = append(matches, sufMatches...)
-	matches = append(matches, preMatches...)
-	matches = append(matches, containMatches...)
-
-	return matches, exactMatch
 
+	app.pkgList = make([]string, 0, len(pkgs))
+	app.srv.Commands = map[string]bool{}
+	for _, pkg := range pkgs {
+		app.pkgList = append(app.pkgList, pkg.ImportPath)
+		if pkg.Command {
+			app.srv.Commands[pkg.ImportPath] = true
+		}
+	}
+	return nil
 }
 
-// scrapePkgPage scrapes the /pkg HTML, returning all pkg
-// names listed on that page.
-func scrapePkgPage(r io.Reader) ([]string, error) {
-	doc, err := goquery.NewDocumentFromReader(r)
+// loadServerPkgList loads the list of pkgs from the server (starting
+// the server if necessary), and sets app.serverPkgList with that data.
+func loadServerPkgList(app *App) error {
+	err := requireServer(app)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var pkgs []string
-
-	selector := ".pkg-dir td.pkg-name a[href]"
-
-	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
-		v, ok := s.Attr("href")
-		if ok {
-			// The link looks like "encoding/json/"
-			v = strings.TrimSuffix(v, "/")
-			pkgs = append(pkgs, v)
-		}
-	})
-
-	return pkgs, nil
+	pkgs, err := app.srv.Packages(app.ctx)
+	if err != nil {
+		return err
+	}
+	app.serverPkgList = pkgs
+	return nil
 }
@@ -3,44 +3,65 @@ package main
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"net"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/shirou/gopsutil/process"
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/server"
 )
 
 // cmdServers lists godoc http server processes.
 func cmdServers(app *App) error {
-	ctx := app.ctx
-	if ctx == nil {
-		ctx = context.Background()
+	if app.ctx == nil {
+		app.ctx = context.Background()
 	}
 
-	ps, err := listServerProcesses(ctx)
+	ps, err := listProcesses(app)
 	if err != nil {
 		return err
 	}
 
+	var exposed int
 	for _, p := range ps {
-		fmt.Println(p)
+		if p.Loopback() {
+			fmt.Println(p)
+			continue
+		}
+
+		exposed++
+		fmt.Printf("%s  [WARNING: %s]\n", p, describeListen(p))
+	}
+
+	if exposed > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d godoc http server(s) accessible from the network: to restrict to loopback, kill with gohdoc -killall\n", exposed)
 	}
 	return nil
 }
 
+// describeListen describes the non-loopback address that server process
+// p listens on.
+func describeListen(p server.Process) string {
+	if p.Port() == 0 {
+		return "listen address not known"
+	}
+	host := p.Host()
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		return "listens on all interfaces"
+	}
+	return "listens on non-loopback address " + host
+}
+
 // cmdKillAll attempts to kill running processes named "godoc" with arg "-http".
 // That is, it attempts to kill all running godoc http servers.
 func cmdKillAll(app *App) error {
-	ctx := app.ctx
-	if ctx == nil {
-		ctx = context.Background()
+	if app.ctx == nil {
+		app.ctx = context.Background()
 	}
 
-	ps, err := listServerProcesses(ctx)
+	ps, err := listProcesses(app)
 	if err != nil {
 		return err
 	}
@@ -48,7 +69,7 @@ func cmdKillAll(app *App) error {
 	var errCount int
 
 	for _, p := range ps {
-		err := p.process.KillWithContext(ctx)
+		err := killProcess(app, p)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s  :  %s\n", p, err)
 			errCount++
@@ -71,167 +92,56 @@ func cmdKillAll(app *App) error {
 	}
 }
 
-// processMeta encapsulate a process.Process and some already-loaded
-// metadata, to avoid having to load the metadata again.
-type processMeta struct {
-	process *process.Process
-	pid     int32
-	// name is process name
-	name     string
-	username string
-	cmdline  []string
-}
-
-func (p processMeta) String() string {
-	username := p.username
-	if username == "" {
-		username = "UNKNOWN_USER"
-	}
-
-	return fmt.Sprintf("%-16s  %-6d  %s", username, p.pid, strings.Join(p.cmdline, " "))
-}
-
-func listServerProcesses(ctx context.Context) ([]processMeta, error) {
-	var matches []processMeta
-
-	ps, err := process.ProcessesWithContext(ctx)
+// selectServerPort sets app.srv.Port to the port of a running godoc http
+// server for which match returns true. If there's no such server, a free
+// port is selected, on which a server can be started.
+func selectServerPort(app *App, match func(p server.Process) bool) error {
+	ps, err := listProcesses(app)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list processes: %v", err)
+		// Not fatal: we can start a new server.
+		log.Printf("failed to list godoc http servers: %v", err)
 	}
 
 	for _, p := range ps {
-		name, err := p.NameWithContext(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get process [%d] name: %v", p.Pid, err)
-		}
-
-		if !strings.HasPrefix(name, "godoc") {
-			continue
-		}
-
-		args, err := p.CmdlineSliceWithContext(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get command line args for process [%d]: %v", p.Pid, err)
-		}
-
-		uname, _ := p.Username() // not critical that we get the uname
-
-		for _, a := range args {
-			if strings.HasPrefix(a, "-http") {
-				// TODO: should refine this to only kill servers on same port as us?
-				log.Printf("found process named godoc [%d] with http server flag [%s]\n",
-					p.Pid, strings.Join(args, " "))
-
-				match := processMeta{process: p, pid: p.Pid, name: name, username: uname, cmdline: args}
-				matches = append(matches, match)
-				break
-			}
+		if p.Port() > 0 && match(p) {
+			log.Printf("found godoc http server [%d] on port %d: %s", p.PID, p.Port(), strings.Join(p.Cmdline, " "))
+			app.srv.Port = p.Port()
+			return nil
 		}
 	}
-	return matches, nil
-}
-
-// requireServer checks if there's an existing godoc http server, or starts one if
-// not. If requireServer returns without an error, app.serverPkgPageBody will have
-// been set to the contents of the godoc http server's /pkg page.
-func requireServer(app *App) (err error) {
-	if len(app.serverPkgPageBody) > 0 {
-		// If this is already set, then we've already determined that a server exists.
-		return nil
-	}
 
-	serverExisted := false
-	pingURL := fmt.Sprintf("http://localhost:%d/pkg", app.port)
-
-	resp, err := http.Get(pingURL)
+	app.srv.Port, err = server.FreePort()
 	if err != nil {
-		log.Printf("apparently there's no existing godoc http server at %s: %v", pingURL, err)
-	} else if resp.StatusCode == http.StatusOK {
-		serverExisted = true
-		log.Println("found existing godoc server at", pingURL)
-
-		defer resp.Body.Close()
-		app.serverPkgPageBody, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read body from %s: %v", pingURL, err)
-		}
-	}
-
-	if !serverExisted {
-		log.Println("no existing godoc server, will attempt to start one, which will continue to run in background after gohdoc exits")
-
-		err = startServer(app)
-		if err != nil {
-			return err
-		}
-	}
-
-	log.Printf("godoc server is running at %s", pingURL)
-	var timeout time.Time
-
-	if !serverExisted {
-		// Check that the newly-started server is accessible
-		timeout = time.Now().Add(time.Second * 2)
-
-		for {
-			if time.Now().After(timeout) {
-				break
-			}
-
-			resp, err = http.Get(pingURL)
-			if err == nil {
-				if resp.StatusCode == http.StatusOK {
-					app.serverPkgPageBody, err = ioutil.ReadAll(resp.Body)
-					if err != nil {
-						return fmt.Errorf("failed to read body from %s: %v", pingURL, err)
-					}
-					_ = resp.Body.Close()
-					break
-				}
-				_ = resp.Body.Close()
-			}
-
-			time.Sleep(time.Millisecond * 100)
-		}
-
-		if err != nil {
-			return fmt.Errorf("failed to access godoc http server: %v", err)
-		} else if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("got %s from %s", resp.Status, pingURL)
-		}
+		return fmt.Errorf("failed to find a free port for godoc http server: %v", err)
 	}
-
+	log.Printf("no matching godoc http server; will start one if necessary on port %d", app.srv.Port)
 	return nil
 }
 
-// startServer starts a godoc http server. On success, the app.Cmd field will
-// be set to the exec.Cmd used to start the server.
-func startServer(app *App) error {
-	var cmd *exec.Cmd
-
-	ctx := app.ctx
-	if ctx == nil {
-		ctx = context.Background()
+// sameGOROOT returns true if server process p uses goroot, where
+// empty goroot means the default GOROOT.
+func sameGOROOT(p server.Process, goroot string) bool {
+	if p.GOROOT() == "" || goroot == "" {
+		return p.GOROOT() == goroot
 	}
+	return filepath.Clean(p.GOROOT()) == filepath.Clean(goroot)
+}
 
-	if app.flagDebug {
-		cmd = exec.CommandContext(ctx, "godoc", fmt.Sprintf("-http=:%d", app.port), "-v", "-index", "-index_throttle=0.5")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-	} else {
-		cmd = exec.CommandContext(ctx, "godoc", fmt.Sprintf("-http=:%d", app.port), "-index", "-index_throttle=0.5")
+// serverEnv implements server.Server.Env. If the module containing dir
+// is built in vendor mode, the server is started with -mod=vendor in
+// GOFLAGS, so that it serves the vendored pkgs, as listed by gohdoc. The
+// server honours replace directives regardless, as it loads the build
+// list via "go list -m all".
+func serverEnv(ctx context.Context, dir string) ([]string, error) {
+	vendor, err := resolve.VendorMode(ctx, dir)
+	if err != nil || !vendor {
+		return nil, err
 	}
 
-	err := cmd.Start()
-	if err != nil {
-		return err
+	goflags := os.Getenv("GOFLAGS")
+	if strings.Contains(goflags, "-mod=vendor") {
+		// The server inherits GOFLAGS
+		return nil, nil
 	}
-	// If the cmd started successfully, assign it to the app.
-	app.cmd = cmd
-
-	log.Printf("Started godoc server [%d] at http://localhost:%d\n", cmd.Process.Pid, app.port)
-	log.Printf("Server will continue to run in the background. Kill with: gohdoc -killall\n\n")
-
-	return nil
+	return []string{"GOFLAGS=" + strings.TrimSpace(goflags+" -mod=vendor")}, nil
 }
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"syscall"
+	"testing"
+)
+
+func TestExecStarterDaemon(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	proc, err := ExecStarter{}.Start(ctx, "", nil, "sleep", []string{"10"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = proc.Kill() }()
+
+	// Cancelling ctx must not kill the process
+	cancel()
+
+	pgid, err := syscall.Getpgid(proc.Pid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pgid != proc.Pid() {
+		t.Errorf("expected process [%d] to lead its own process group, but its group is %d", proc.Pid(), pgid)
+	}
+	if err = syscall.Kill(proc.Pid(), 0); err != nil {
+		t.Errorf("expected process [%d] to be running after ctx cancel: %v", proc.Pid(), err)
+	}
+}
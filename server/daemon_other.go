@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package server
+
+import "os/exec"
+
+// daemonize does nothing: on this platform, the process is started
+// as an ordinary child of gohdoc.
+func daemonize(cmd *exec.Cmd) {}
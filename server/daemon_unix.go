@@ -0,0 +1,15 @@
+//go:build unix
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// daemonize configures cmd to start in its own session (and thus its own
+// process group), without a controlling terminal, so that it isn't sent
+// the signals of gohdoc's terminal, e.g. on Ctrl-C or terminal close.
+func daemonize(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is the DETACHED_PROCESS process creation flag.
+const detachedProcess = 0x00000008
+
+// daemonize configures cmd to start in its own process group, detached
+// from gohdoc's console, so that it isn't sent the console's signals,
+// e.g. on Ctrl-C or console close.
+func daemonize(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// HTTPClient sends HTTP requests. *http.Client implements HTTPClient.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Starter starts the godoc http server process.
+type Starter interface {
+	// Start starts the process name with args, in dir (or the current
+	// dir, if dir is empty). If env is non-nil, it is the process's
+	// environment; otherwise the process inherits gohdoc's environment.
+	// If out is non-nil, the process's stdout and stderr are written to it.
+	// The process should continue to run after gohdoc exits: its lifetime
+	// is not tied to ctx.
+	Start(ctx context.Context, dir string, env []string, name string, args []string, out io.Writer) (Proc, error)
+}
+
+// Proc is a handle to a process started by a Starter.
+type Proc interface {
+	Pid() int
+	Kill() error
+}
+
+// Clock tells the time, and sleeps.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// ExecStarter is the Starter that uses os/exec. The process is started
+// as a daemon: it is independent of ctx, is in its own session (or
+// process group), and its stdin is detached, as are its stdout and
+// stderr unless out is non-nil. Thus it continues to run after gohdoc
+// is interrupted or its terminal is closed, but it can still be killed
+// via Proc, or by gohdoc -killall.
+type ExecStarter struct{}
+
+// Start implements Starter.
+func (ExecStarter) Start(ctx context.Context, dir string, env []string, name string, args []string, out io.Writer) (Proc, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	if out != nil {
+		cmd.Stdout = out
+		cmd.Stderr = out
+	}
+	daemonize(cmd)
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	return execProc{cmd: cmd}, nil
+}
+
+type execProc struct {
+	cmd *exec.Cmd
+}
+
+func (p execProc) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+func (p execProc) Kill() error {
+	return p.cmd.Process.Kill()
+}
+
+// SystemClock is the Clock that uses package time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// HTTPClient returns s.Client, or http.DefaultClient if s.Client is nil.
+func (s *Server) HTTPClient() HTTPClient {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *Server) starter() Starter {
+	if s.Starter != nil {
+		return s.Starter
+	}
+	return ExecStarter{}
+}
+
+func (s *Server) clock() Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return SystemClock
+}
+
+// Do sends req using s.HTTPClient, after authorizing it with s.Auth.
+// Thus s itself is a HTTPClient.
+func (s *Server) Do(req *http.Request) (*http.Response, error) {
+	s.Auth.Authorize(req)
+	return s.HTTPClient().Do(req)
+}
+
+// do sends a request with method to url.
+func (s *Server) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.Do(req.WithContext(ctx))
+}
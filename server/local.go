@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ServeLocal serves handler on a random loopback port. It returns the
+// server's base URL, e.g. "http://127.0.0.1:54321", and a func that
+// stops the server. Unlike Server, this is not a godoc http server:
+// it's for pages that gohdoc itself serves, e.g. the -watch proxy.
+func ServeLocal(handler http.Handler) (baseURL *url.URL, stop func(), err error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv := &http.Server{Handler: handler}
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("local server failed: %v", err)
+		}
+	}()
+
+	log.Printf("started local server at %s", ln.Addr())
+	return &url.URL{Scheme: "http", Host: ln.Addr().String(), Path: "/"}, func() { _ = srv.Close() }, nil
+}
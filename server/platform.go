@@ -0,0 +1,31 @@
+package server
+
+import "net/url"
+
+// Platform is the target platform for which pkg docs are rendered, e.g.
+// to view the windows or arm64 API of a pkg from a linux workstation.
+// The zero value is the server's own platform.
+type Platform struct {
+	// GOOS and GOARCH, if set, are passed to the server with each pkg
+	// page request, via godoc's GOOS and GOARCH URL params. Thus a
+	// running server can render the docs of any platform.
+	GOOS   string
+	GOARCH string
+
+	// Tags are additional build tags, used only when loading pkgs locally
+	// (e.g. for -source or -lint). They don't affect the server: godoc
+	// renders pages without build tags.
+	Tags []string
+}
+
+// params returns the URL query params that select the platform.
+func (p Platform) params() url.Values {
+	vals := url.Values{}
+	if p.GOOS != "" {
+		vals.Set("GOOS", p.GOOS)
+	}
+	if p.GOARCH != "" {
+		vals.Set("GOARCH", p.GOARCH)
+	}
+	return vals
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// Process is a godoc http server process, with some already-loaded
+// metadata, to avoid having to load the metadata again.
+type Process struct {
+	PID int32
+	// Name is the process name.
+	Name     string
+	Username string
+	Cmdline  []string
+	// Dir is the process's current dir, if known. In module mode, godoc
+	// serves the module in its current dir.
+	Dir string
+
+	process *process.Process
+}
+
+func (p Process) String() string {
+	username := p.Username
+	if username == "" {
+		username = "UNKNOWN_USER"
+	}
+
+	return fmt.Sprintf("%-16s  %-6d  %s", username, p.PID, strings.Join(p.Cmdline, " "))
+}
+
+// Port returns the port of the process's -http flag,
+// e.g. 6060 for "-http=:6060", or 0 if not known.
+func (p Process) Port() int {
+	_, port, ok := p.httpAddr()
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Host returns the host of the process's -http flag, e.g. "localhost"
+// for "-http=localhost:6060", or empty string for "-http=:6060", in
+// which case the server listens on all interfaces.
+func (p Process) Host() string {
+	host, _, _ := p.httpAddr()
+	return host
+}
+
+// Loopback returns true if the process's -http flag binds the server
+// to loopback, e.g. "-http=localhost:6060" or "-http=127.0.0.1:6060",
+// such that it isn't accessible from the network.
+func (p Process) Loopback() bool {
+	host, _, ok := p.httpAddr()
+	if !ok {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// httpAddr returns the host and port of the process's -http flag.
+func (p Process) httpAddr() (host, port string, ok bool) {
+	addr, ok := p.flagValue("http")
+	if !ok {
+		return "", "", false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", false
+	}
+	return host, port, true
+}
+
+// GOROOT returns the value of the process's -goroot flag, i.e. the GOROOT
+// of the toolchain that provides the std lib docs, or empty string if the
+// flag isn't set (in which case the server uses its default GOROOT).
+func (p Process) GOROOT() string {
+	goroot, _ := p.flagValue("goroot")
+	return goroot
+}
+
+// flagValue returns the value of the process's flag name, given in any
+// of the forms "-name=value", "--name=value" or "-name value".
+func (p Process) flagValue(name string) (value string, ok bool) {
+	for i, arg := range p.Cmdline {
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		switch {
+		case strings.HasPrefix(arg, name+"="):
+			return strings.TrimPrefix(arg, name+"="), true
+		case arg == name && i+1 < len(p.Cmdline):
+			return p.Cmdline[i+1], true
+		}
+	}
+	return "", false
+}
+
+// Kill kills the process.
+func (p Process) Kill(ctx context.Context) error {
+	return p.process.KillWithContext(ctx)
+}
+
+// ListProcesses returns the running processes named "godoc" with
+// arg "-http". That is, it returns all running godoc http servers.
+func ListProcesses(ctx context.Context) ([]Process, error) {
+	var matches []Process
+
+	ps, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	for _, p := range ps {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get process [%d] name: %v", p.Pid, err)
+		}
+
+		if !strings.HasPrefix(name, "godoc") {
+			continue
+		}
+
+		args, err := p.CmdlineSliceWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get command line args for process [%d]: %v", p.Pid, err)
+		}
+
+		uname, _ := p.Username() // not critical that we get the uname
+		cwd, _ := p.CwdWithContext(ctx)
+
+		for _, a := range args {
+			if strings.HasPrefix(a, "-http") {
+				// TODO: should refine this to only kill servers on same port as us?
+				log.Printf("found process named godoc [%d] with http server flag [%s]\n",
+					p.Pid, strings.Join(args, " "))
+
+				match := Process{PID: p.Pid, Name: name, Username: uname, Cmdline: args, Dir: cwd, process: p}
+				matches = append(matches, match)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,616 @@
+// Package server manages a godoc http server: locating or starting
+// one, and constructing and checking its page URLs.
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/neilotoole/gohdoc/scrape"
+)
+
+// DefaultPort is the port that a godoc http server listens on by default.
+const DefaultPort = 6060
+
+// DefaultListen is the default Server.Listen: a started server is bound
+// to the IPv4 loopback address, and thus isn't accessible from the network.
+// The godoc -http flag takes a single address, so the server isn't also
+// bound to ::1; use Listen "::1" for an IPv6-only loopback server.
+const DefaultListen = "127.0.0.1"
+
+// DefaultStartTimeout is the default Server.StartTimeout.
+const DefaultStartTimeout = time.Second * 2
+
+// DefaultProbeTimeout is the default Server.ProbeTimeout.
+const DefaultProbeTimeout = time.Second * 2
+
+// DefaultRetryTimeout is the default Server.RetryTimeout.
+const DefaultRetryTimeout = time.Millisecond * 500
+
+// Server is a godoc http server, either on localhost or remote.
+type Server struct {
+	// Port is the port the server listens on, and the port to start
+	// a server on, if necessary to do so. It is ignored if URL is set.
+	Port int
+
+	// Listen is the host address that a started server listens on, e.g.
+	// "0.0.0.0" to listen on all interfaces, and thus share the server on
+	// the network. An IPv6 address may be in brackets, e.g. "[::1]". If
+	// empty, DefaultListen is used.
+	Listen string
+
+	// URL is the base URL of a remote server, e.g.
+	// "https://godoc.example.com/docs". If nil, the server is
+	// on localhost:Port (or Listen:Port, see BaseURL). A remote server is never started.
+	URL *url.URL
+
+	// Auth holds the credentials for a remote server.
+	Auth Auth
+
+	// Mode selects the page mode used when constructing pkg page URLs.
+	Mode PageMode
+
+	// Platform is the target platform for which pkg pages are rendered.
+	Platform Platform
+
+	// Commands is the set of pkgs that are commands (main pkgs), e.g.
+	// "cmd/go" or "github.com/my/tool", for which godoc serves a command
+	// page under /cmd/ rather than /pkg/.
+	Commands map[string]bool
+
+	// Dir is the dir to start the server in. When Dir is in a module,
+	// godoc serves the docs of that module, and of its dependencies at
+	// the versions required by go.mod. If empty, the current dir is used.
+	Dir string
+
+	// GOROOT, if set, is the GOROOT of the Go toolchain that provides
+	// the std lib docs. A started server is passed GOROOT via godoc's
+	// -goroot flag (and thus can be identified by Process.GOROOT).
+	// If empty, the server uses its default GOROOT.
+	GOROOT string
+
+	// Env, if non-nil, returns the environment variables (e.g.
+	// "GOFLAGS=-mod=vendor") to set for a server started in dir, in
+	// addition to gohdoc's environment.
+	Env func(ctx context.Context, dir string) ([]string, error)
+
+	// Debug, if true, starts the server in verbose mode, with its
+	// output sent to stdout/stderr.
+	Debug bool
+
+	// Client sends the requests to the server. If nil, http.DefaultClient
+	// is used.
+	Client HTTPClient
+
+	// Starter starts the server process. If nil, ExecStarter is used.
+	Starter Starter
+
+	// Clock is used to wait for a newly-started server. If nil,
+	// SystemClock is used.
+	Clock Clock
+
+	// StartTimeout is how long to wait for a newly-started server to
+	// become accessible. If zero, DefaultStartTimeout is used.
+	StartTimeout time.Duration
+
+	// ProbeTimeout is the deadline for each request made by PkgPageOK.
+	// If zero, DefaultProbeTimeout is used.
+	ProbeTimeout time.Duration
+
+	// RetryTimeout is how long PkgPageOK keeps retrying (with exponential
+	// backoff) when invoked with retry true. If zero, DefaultRetryTimeout
+	// is used.
+	RetryTimeout time.Duration
+
+	// OnProbe, if non-nil, is invoked for each request made by PkgPageOK,
+	// e.g. to explain how a pkg arg was resolved. PkgPageOK may be invoked
+	// concurrently, but the OnProbe invocations are serialized.
+	OnProbe func(p Probe)
+
+	// Proc is the server process, if Start was invoked.
+	// It is nil if the server pre-existed.
+	Proc Proc
+
+	// pkgPage holds the contents of the server's /pkg/ page, once Require
+	// has determined that the server is available. It is guarded by mu,
+	// so that concurrent invocations of Require start at most one server.
+	pkgPage []byte
+	mu      sync.Mutex
+	// available is 1 if pkgPage is set. Unlike pkgPage, it's accessed
+	// atomically rather than via mu, so that Available doesn't wait for
+	// a Require that's waiting for a newly-started server.
+	available int32
+
+	// probeMu serializes the invocations of OnProbe.
+	probeMu sync.Mutex
+}
+
+// PageMode is the godoc page mode (?m=all, ?m=src).
+type PageMode struct {
+	// All shows unexported identifiers.
+	All bool
+	// Src shows source instead of doc.
+	Src bool
+}
+
+// Query returns the URL query (including the leading "?") that
+// selects the godoc page mode, or empty string for the default mode.
+// The godoc http server accepts a comma-separated list of modes via the
+// "m" param, e.g. "?m=all,src".
+func (m PageMode) Query() string {
+	var modes []string
+	if m.All {
+		modes = append(modes, "all")
+	}
+	if m.Src {
+		modes = append(modes, "src")
+	}
+
+	if len(modes) == 0 {
+		return ""
+	}
+	return "?m=" + strings.Join(modes, ",")
+}
+
+// BaseURL returns the server URL, without trailing slash, e.g.
+// "http://localhost:6060" or "https://godoc.example.com/docs".
+// For a local server, the host is localhost, unless s.Listen is a
+// specific address (not loopback, nor all interfaces), on which the
+// server isn't reachable via localhost.
+func (s *Server) BaseURL() string {
+	if s.URL != nil {
+		return strings.TrimSuffix(s.URL.String(), "/")
+	}
+
+	host := "localhost"
+	listen := s.listenHost()
+	if ip := net.ParseIP(listen); listen != "localhost" && (ip == nil || !ip.IsLoopback() && !ip.IsUnspecified()) {
+		host = listen
+	}
+	return "http://" + net.JoinHostPort(host, fmt.Sprint(s.Port))
+}
+
+// listenHost returns the host address that a started server listens on:
+// s.Listen without any brackets, or DefaultListen.
+func (s *Server) listenHost() string {
+	if s.Listen == "" {
+		return DefaultListen
+	}
+	if strings.HasPrefix(s.Listen, "[") && strings.HasSuffix(s.Listen, "]") {
+		return s.Listen[1 : len(s.Listen)-1]
+	}
+	return s.Listen
+}
+
+// Remote returns true if the server is remote, i.e. s.URL is set.
+func (s *Server) Remote() bool {
+	return s.URL != nil
+}
+
+// PkgURL returns the server URL for the supplied pkg. The URL query
+// selects the page mode s.Mode, and the platform s.Platform. If the pkg
+// is a command (see s.Commands), the command page URL is returned, e.g.
+// "http://localhost:6060/cmd/go/".
+func (s *Server) PkgURL(fullPkgPath string, fragment string) string {
+	return s.pageURL(fullPkgPath, fragment, s.Mode)
+}
+
+// pageURL returns the server URL for pkg, in page mode.
+func (s *Server) pageURL(fullPkgPath string, fragment string, mode PageMode) string {
+	fullPkgPath = strings.TrimPrefix(fullPkgPath, "/")
+	fragment = strings.TrimSuffix(fragment, "#")
+
+	// godoc serves the std commands (e.g. "cmd/go") at /cmd/go/, and
+	// other commands at /cmd/IMPORT_PATH/.
+	dir := "pkg"
+	if s.Commands[fullPkgPath] {
+		dir = "cmd"
+		fullPkgPath = strings.TrimPrefix(fullPkgPath, "cmd/")
+	}
+
+	query := mode.Query()
+	if params := s.Platform.params(); len(params) > 0 {
+		if query == "" {
+			query = "?" + params.Encode()
+		} else {
+			query += "&" + params.Encode()
+		}
+	}
+
+	if len(fragment) == 0 {
+		return fmt.Sprintf("%s/%s/%s/%s", s.BaseURL(), dir, fullPkgPath, query)
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s#%s", s.BaseURL(), dir, fullPkgPath, query, fragment)
+}
+
+// SrcURL returns the server source view URL for line
+// of filename in pkg.
+func (s *Server) SrcURL(fullPkgPath, filename string, line int) string {
+	fullPkgPath = strings.TrimPrefix(fullPkgPath, "/")
+	return fmt.Sprintf("%s/src/%s/%s#L%d", s.BaseURL(), fullPkgPath, filename, line)
+}
+
+// Require checks if there's an existing godoc http server, or starts one if
+// not (unless the server is remote). On success, it returns the contents
+// of the server's /pkg page.
+// Require may be invoked concurrently.
+func (s *Server) Require(ctx context.Context) (pkgPage []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pkgPage) > 0 {
+		// If this is already set, then we've already determined that a server exists.
+		return s.pkgPage, nil
+	}
+
+	serverExisted := false
+	pingURL := s.BaseURL() + "/pkg"
+
+	resp, err := s.do(ctx, http.MethodGet, pingURL)
+	if err != nil {
+		log.Printf("apparently there's no existing godoc http server at %s: %v", pingURL, err)
+	} else if resp.StatusCode == http.StatusOK {
+		serverExisted = true
+		log.Println("found existing godoc server at", pingURL)
+
+		defer resp.Body.Close()
+		s.pkgPage, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body from %s: %v", pingURL, err)
+		}
+	}
+
+	if !serverExisted && s.Remote() {
+		if err != nil {
+			return nil, fmt.Errorf("failed to access godoc http server %s: %v", s.BaseURL(), err)
+		}
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("got %s from %s", resp.Status, pingURL)
+	}
+
+	if !serverExisted {
+		log.Println("no existing godoc server, will attempt to start one, which will continue to run in background after gohdoc exits")
+
+		err = s.Start(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("godoc server is running at %s", pingURL)
+	var timeout time.Time
+
+	if !serverExisted {
+		// Check that the newly-started server is accessible
+		startTimeout := s.StartTimeout
+		if startTimeout == 0 {
+			startTimeout = DefaultStartTimeout
+		}
+		timeout = s.clock().Now().Add(startTimeout)
+
+		for {
+			if s.clock().Now().After(timeout) {
+				break
+			}
+			if ctx.Err() != nil {
+				// e.g. gohdoc was interrupted
+				return nil, fmt.Errorf("gave up waiting for godoc http server to start: %v", ctx.Err())
+			}
+
+			resp, err = s.do(ctx, http.MethodGet, pingURL)
+			if err == nil {
+				if resp.StatusCode == http.StatusOK {
+					s.pkgPage, err = ioutil.ReadAll(resp.Body)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read body from %s: %v", pingURL, err)
+					}
+					_ = resp.Body.Close()
+					break
+				}
+				_ = resp.Body.Close()
+			}
+
+			s.clock().Sleep(time.Millisecond * 100)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to access godoc http server: %v", err)
+		} else if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("got %s from %s", resp.Status, pingURL)
+		}
+	}
+
+	if len(s.pkgPage) > 0 {
+		atomic.StoreInt32(&s.available, 1)
+	}
+	return s.pkgPage, nil
+}
+
+// Start starts a godoc http server. On success, the s.Proc field will
+// be set to the started process.
+func (s *Server) Start(ctx context.Context) error {
+	args := []string{"-http=" + net.JoinHostPort(s.listenHost(), fmt.Sprint(s.Port)), "-index", "-index_throttle=0.5"}
+	if s.GOROOT != "" {
+		args = append(args, "-goroot="+s.GOROOT)
+	}
+	var out io.Writer
+
+	if s.Debug {
+		args = append(args, "-v")
+		out = os.Stdout
+	}
+
+	var env []string
+	if s.Env != nil {
+		extra, err := s.Env(ctx, s.Dir)
+		if err != nil {
+			// Not fatal: the server inherits gohdoc's environment
+			log.Printf("failed to determine environment for godoc http server: %v", err)
+		}
+		if len(extra) > 0 {
+			env = append(os.Environ(), extra...)
+		}
+	}
+
+	proc, err := s.starter().Start(ctx, s.Dir, env, "godoc", args, out)
+	if err != nil {
+		return err
+	}
+	// If the process started successfully, assign it to the server.
+	s.Proc = proc
+
+	log.Printf("Started godoc server [%d] at %s\n", proc.Pid(), s.BaseURL())
+	log.Printf("Server will continue to run in the background. Kill with: gohdoc -killall\n\n")
+
+	return nil
+}
+
+// FreePort returns a port that is currently free on localhost.
+func FreePort() (int, error) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// PortFree returns true if port is free, i.e. no server (such as a
+// godoc http server) is listening on it.
+func PortFree(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
+
+// WaitPortFree waits for s.Port to be free (see PortFree), as after the
+// server listening on it has been killed, sleeping via s.Clock between
+// checks. It gives up after a couple of seconds, returning false.
+func (s *Server) WaitPortFree() bool {
+	for i := 0; i < 20; i++ {
+		if PortFree(s.Port) {
+			return true
+		}
+		s.clock().Sleep(time.Millisecond * 100)
+	}
+	return PortFree(s.Port)
+}
+
+// Available returns true if Require has found the server to be
+// available (and Reset hasn't since been invoked). Unlike Require, it
+// doesn't block, so it may be invoked while Require is waiting for a
+// newly-started server, e.g. on interrupt.
+func (s *Server) Available() bool {
+	return atomic.LoadInt32(&s.available) == 1
+}
+
+// Reset forgets that the server was found to be available (e.g. because
+// Port has changed, or the server was killed), so that the next Require
+// checks again.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pkgPage = nil
+	atomic.StoreInt32(&s.available, 0)
+}
+
+// Packages returns the list of pkgs on the server, starting
+// the server if necessary.
+func (s *Server) Packages(ctx context.Context) ([]string, error) {
+	body, err := s.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) == 0 {
+		return nil, errors.New("apparently no data from godoc http server /pkg")
+	}
+
+	return scrape.Packages(bytes.NewReader(body))
+}
+
+// CommandPackages returns the list of std commands (e.g. "cmd/go") on
+// the server, as listed on its /cmd/ page. The server is not started.
+func (s *Server) CommandPackages(ctx context.Context) ([]string, error) {
+	pageURL := s.BaseURL() + "/cmd/"
+	resp, err := s.do(ctx, http.MethodGet, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access godoc http server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %s from %s", resp.Status, pageURL)
+	}
+
+	names, err := scrape.Packages(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", pageURL, err)
+	}
+
+	var cmds []string
+	for _, name := range names {
+		cmds = append(cmds, "cmd/"+name)
+	}
+	return cmds, nil
+}
+
+// PkgPageOK returns true, nil if pkgPath exists on the server.
+// The pkgPath arg should be a well-formed pkg path, e.g. "bytes", "encoding/json",
+// or "github.com/neilotoole/gohdoc".
+// Each request has deadline s.ProbeTimeout. If retry is false, a single
+// request is made. If retry is true (e.g. because a newly-started server
+// may still be loading pkgs), failed requests are retried with exponential
+// backoff until s.RetryTimeout has elapsed.
+// An error is returned if a http failure occurs.
+func (s *Server) PkgPageOK(ctx context.Context, pkgPath string, retry bool) (ok bool, err error) {
+	if strings.HasPrefix(pkgPath, "/") || strings.HasSuffix(pkgPath, "/") {
+		return false, fmt.Errorf("invalid pkg path (has '/' prefix or suffix): %s", pkgPath)
+	}
+
+	pageURL := s.PkgURL(pkgPath, "")
+
+	retryTimeout := s.RetryTimeout
+	if retryTimeout == 0 {
+		retryTimeout = DefaultRetryTimeout
+	}
+	deadline := s.clock().Now().Add(retryTimeout)
+	backoff := time.Millisecond * 50
+
+	for i := 1; ; i++ {
+		log.Printf("verifying pkg page (attempt %d): %s\n", i, pageURL)
+		ok, err = s.probe(ctx, pageURL, i)
+		if ok || !retry || ctx.Err() != nil {
+			break
+		}
+
+		remaining := deadline.Sub(s.clock().Now())
+		if remaining <= 0 {
+			break
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		s.clock().Sleep(backoff)
+		backoff *= 2
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to access godoc http server: %v", err)
+	}
+	return ok, nil
+}
+
+// probe makes a HEAD request (with deadline s.ProbeTimeout) to pageURL,
+// returning true if the response is 200 OK.
+func (s *Server) probe(ctx context.Context, pageURL string, attempt int) (bool, error) {
+	timeout := s.ProbeTimeout
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := s.clock().Now()
+	resp, err := s.do(ctx, http.MethodHead, pageURL)
+	s.probed(pageURL, attempt, start, resp, err)
+	if err != nil {
+		return false, err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// Probe is a request made by PkgPageOK to check that a pkg page
+// is available on the server.
+type Probe struct {
+	URL string
+	// Attempt is the attempt number, starting at 1, for URL.
+	Attempt int
+	// Status is the response status code, or zero if Err is non-nil.
+	Status int
+	Err    error
+	// Duration is how long the request took.
+	Duration time.Duration
+}
+
+// probed invokes s.OnProbe, if set, for a request to url that
+// started at start, with outcome resp and err.
+func (s *Server) probed(url string, attempt int, start time.Time, resp *http.Response, err error) {
+	if s.OnProbe == nil {
+		return
+	}
+
+	p := Probe{URL: url, Attempt: attempt, Err: err, Duration: s.clock().Now().Sub(start)}
+	if err == nil {
+		p.Status = resp.StatusCode
+	}
+
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+	s.OnProbe(p)
+}
+
+// PkgPageIDs returns the ids of the elements of the server page for pkg,
+// e.g. "Println" or "Buffer.Len" for symbols. The page is the doc page
+// (rather than the source page, if s.Mode.Src is set).
+func (s *Server) PkgPageIDs(ctx context.Context, pkg string) ([]string, error) {
+	pageURL := s.pageURL(pkg, "", PageMode{All: s.Mode.All})
+	log.Printf("reading ids of %s", pageURL)
+
+	resp, err := s.do(ctx, http.MethodGet, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access godoc http server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %s from %s", resp.Status, pageURL)
+	}
+
+	ids, err := scrape.IDs(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", pageURL, err)
+	}
+	return ids, nil
+}
+
+// PkgPageHasID returns true if the server page for pkg has an
+// element with the supplied id, e.g. "Println" or "Buffer.Len".
+func (s *Server) PkgPageHasID(ctx context.Context, pkg, id string) (bool, error) {
+	pageURL := s.PkgURL(pkg, "")
+	log.Printf("verifying that %s has id %q", pageURL, id)
+
+	resp, err := s.do(ctx, http.MethodGet, pageURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to access godoc http server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("got %s from %s", resp.Status, pageURL)
+	}
+
+	ok, err := scrape.HasID(resp.Body, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %v", pageURL, err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPkgURL(t *testing.T) {
+	testCases := []struct {
+		pkg  string
+		frag string
+		all  bool
+		src  bool
+		// goos and goarch select the platform
+		goos   string
+		goarch string
+		want   string
+	}{
+		{pkg: "fmt", want: "http://localhost:6060/pkg/fmt/"},
+		{pkg: "/fmt", want: "http://localhost:6060/pkg/fmt/"},
+		{pkg: "fmt", frag: "Println", want: "http://localhost:6060/pkg/fmt/#Println"},
+		{pkg: "fmt", frag: "newPrinter", all: true, want: "http://localhost:6060/pkg/fmt/?m=all#newPrinter"},
+		{pkg: "fmt", src: true, want: "http://localhost:6060/pkg/fmt/?m=src"},
+		{pkg: "fmt", all: true, src: true, want: "http://localhost:6060/pkg/fmt/?m=all,src"},
+		{pkg: "cmd/go", want: "http://localhost:6060/cmd/go/"},
+		{pkg: "cmd/go", src: true, want: "http://localhost:6060/cmd/go/?m=src"},
+		{pkg: "github.com/my/tool", frag: "hdr-Usage", want: "http://localhost:6060/cmd/github.com/my/tool/#hdr-Usage"},
+		{pkg: "cmd/internal/obj", want: "http://localhost:6060/pkg/cmd/internal/obj/"},
+		{pkg: "os", goos: "windows", want: "http://localhost:6060/pkg/os/?GOOS=windows"},
+		{pkg: "os", frag: "Getpagesize", goos: "windows", goarch: "arm64", all: true,
+			want: "http://localhost:6060/pkg/os/?m=all&GOARCH=arm64&GOOS=windows#Getpagesize"},
+		{pkg: "cmd/go", goarch: "386", want: "http://localhost:6060/cmd/go/?GOARCH=386"},
+	}
+
+	for i, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d__%s", i, tc.want), func(t *testing.T) {
+			s := &Server{Port: 6060, Mode: PageMode{All: tc.all, Src: tc.src},
+				Platform: Platform{GOOS: tc.goos, GOARCH: tc.goarch},
+				Commands: map[string]bool{"cmd/go": true, "github.com/my/tool": true}}
+			got := s.PkgURL(tc.pkg, tc.frag)
+			if got != tc.want {
+				t.Errorf("want %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSrcURL(t *testing.T) {
+	s := &Server{Port: 6060}
+	const want = "http://localhost:6060/src/fmt/print.go#L273"
+	got := s.SrcURL("fmt", "print.go", 273)
+	if got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+}
+
+func TestProcessPort(t *testing.T) {
+	testCases := []struct {
+		cmdline []string
+		want    int
+	}{
+		{cmdline: []string{"godoc", "-http=:6060", "-index"}, want: 6060},
+		{cmdline: []string{"godoc", "-v", "--http=localhost:6061"}, want: 6061},
+		{cmdline: []string{"godoc", "-http", "127.0.0.1:6062"}, want: 6062},
+		{cmdline: []string{"godoc", "-http=bogus"}, want: 0},
+		{cmdline: []string{"godoc"}, want: 0},
+	}
+
+	for _, tc := range testCases {
+		p := Process{Cmdline: tc.cmdline}
+		if got := p.Port(); got != tc.want {
+			t.Errorf("%v: want %d but got %d", tc.cmdline, tc.want, got)
+		}
+	}
+}
+
+func TestProcessLoopback(t *testing.T) {
+	testCases := []struct {
+		cmdline []string
+		want    bool
+	}{
+		{cmdline: []string{"godoc", "-http=localhost:6060"}, want: true},
+		{cmdline: []string{"godoc", "-http", "127.0.0.1:6060"}, want: true},
+		{cmdline: []string{"godoc", "-http=[::1]:6060"}, want: true},
+		{cmdline: []string{"godoc", "-http=:6060"}, want: false},
+		{cmdline: []string{"godoc", "-http=0.0.0.0:6060"}, want: false},
+		{cmdline: []string{"godoc", "-http=192.168.1.5:6060"}, want: false},
+		{cmdline: []string{"godoc", "-http=bogus"}, want: false},
+	}
+
+	for _, tc := range testCases {
+		p := Process{Cmdline: tc.cmdline}
+		if got := p.Loopback(); got != tc.want {
+			t.Errorf("%v: want %v but got %v", tc.cmdline, tc.want, got)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	testCases := []struct {
+		listen   string
+		wantArg  string
+		wantBase string
+	}{
+		{listen: "", wantArg: "-http=127.0.0.1:6060", wantBase: "http://localhost:6060"},
+		{listen: "127.0.0.1", wantArg: "-http=127.0.0.1:6060", wantBase: "http://localhost:6060"},
+		{listen: "localhost", wantArg: "-http=localhost:6060", wantBase: "http://localhost:6060"},
+		{listen: "::1", wantArg: "-http=[::1]:6060", wantBase: "http://localhost:6060"},
+		{listen: "[::1]", wantArg: "-http=[::1]:6060", wantBase: "http://localhost:6060"},
+		{listen: "0.0.0.0", wantArg: "-http=0.0.0.0:6060", wantBase: "http://localhost:6060"},
+		{listen: "::", wantArg: "-http=[::]:6060", wantBase: "http://localhost:6060"},
+		{listen: "192.168.1.5", wantArg: "-http=192.168.1.5:6060", wantBase: "http://192.168.1.5:6060"},
+		{listen: "fd00::5", wantArg: "-http=[fd00::5]:6060", wantBase: "http://[fd00::5]:6060"},
+		{listen: "[fd00::5]", wantArg: "-http=[fd00::5]:6060", wantBase: "http://[fd00::5]:6060"},
+	}
+
+	for _, tc := range testCases {
+		starter := &recordStarter{}
+		s := &Server{Port: DefaultPort, Listen: tc.listen, Starter: starter}
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(starter.args) == 0 || starter.args[0] != tc.wantArg {
+			t.Errorf("%q: want server started with %s but got %v", tc.listen, tc.wantArg, starter.args)
+		}
+		if got := s.BaseURL(); got != tc.wantBase {
+			t.Errorf("%q: want base URL %q but got %q", tc.listen, tc.wantBase, got)
+		}
+	}
+}
+
+// recordStarter is a Starter that records the args it was invoked
+// with, instead of starting a process.
+type recordStarter struct {
+	args []string
+}
+
+func (s *recordStarter) Start(ctx context.Context, dir string, env []string, name string, args []string, out io.Writer) (Proc, error) {
+	s.args = args
+	return recordProc{}, nil
+}
+
+type recordProc struct{}
+
+func (recordProc) Pid() int    { return 42 }
+func (recordProc) Kill() error { return nil }
+
+func TestProcessGOROOT(t *testing.T) {
+	testCases := []struct {
+		cmdline []string
+		want    string
+	}{
+		{cmdline: []string{"godoc", "-http=:6060", "-goroot=/home/me/sdk/go1.21.0"}, want: "/home/me/sdk/go1.21.0"},
+		{cmdline: []string{"godoc", "-goroot", "/usr/local/go", "-http=:6060"}, want: "/usr/local/go"},
+		{cmdline: []string{"godoc", "-http=:6060"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		p := Process{Cmdline: tc.cmdline}
+		if got := p.GOROOT(); got != tc.want {
+			t.Errorf("%v: want %q but got %q", tc.cmdline, tc.want, got)
+		}
+	}
+}
+
+// sleepClock is a Clock for which Sleep returns immediately,
+// recording the sleep durations.
+type sleepClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *sleepClock) Now() time.Time { return c.now }
+
+func (c *sleepClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// downClient is a HTTPClient for a server that isn't (yet) listening.
+type downClient struct{}
+
+func (downClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+// interruptClock is a sleepClock that invokes onSleep before sleeping.
+type interruptClock struct {
+	sleepClock
+	onSleep func()
+}
+
+func (c *interruptClock) Sleep(d time.Duration) {
+	c.onSleep()
+	c.sleepClock.Sleep(d)
+}
+
+func TestRequireInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := &interruptClock{sleepClock: sleepClock{now: time.Now()}}
+	s := &Server{Port: DefaultPort, Starter: &recordStarter{}, Client: downClient{}, Clock: clock, StartTimeout: time.Hour}
+	clock.onSleep = func() {
+		// As on interrupt, while Require waits for the started server:
+		// Available must not wait for Require to return.
+		if s.Available() {
+			t.Error("want server not available")
+		}
+		cancel()
+	}
+
+	_, err := s.Require(ctx)
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting") {
+		t.Errorf("want error on interrupt but got %v", err)
+	}
+	if len(clock.sleeps) != 1 {
+		t.Errorf("want Require to return on interrupt, but it slept %d times", len(clock.sleeps))
+	}
+	if s.Available() {
+		t.Error("want server not available")
+	}
+}
+
+func TestPkgPageOK(t *testing.T) {
+	// The pkg page is found from request number foundAt
+	requests, foundAt := 0, 3
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < foundAt {
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &sleepClock{now: time.Now()}
+	var probes []Probe
+	s := &Server{URL: u, Clock: clock, OnProbe: func(p Probe) { probes = append(probes, p) }}
+
+	// Without retry, a single request is made
+	ok, err := s.PkgPageOK(context.Background(), "fmt", false)
+	if ok || err != nil || len(probes) != 1 || probes[0].Status != http.StatusNotFound {
+		t.Fatalf("want single 404 probe but got %v, %v, %+v", ok, err, probes)
+	}
+
+	// With retry, the requests back off exponentially
+	ok, err = s.PkgPageOK(context.Background(), "fmt", true)
+	if !ok || err != nil {
+		t.Fatalf("want ok but got %v, %v", ok, err)
+	}
+	wantSleeps := []time.Duration{time.Millisecond * 50}
+	if !reflect.DeepEqual(clock.sleeps, wantSleeps) || len(probes) != 3 || probes[2].Attempt != 2 {
+		t.Errorf("want sleeps %v and 3 probes but got %v, %+v", wantSleeps, clock.sleeps, probes)
+	}
+
+	// The retries stop at the deadline
+	requests, foundAt, clock.sleeps = 0, 100, nil
+	s.RetryTimeout = time.Millisecond * 120
+	ok, err = s.PkgPageOK(context.Background(), "fmt", true)
+	wantSleeps = []time.Duration{time.Millisecond * 50, time.Millisecond * 70}
+	if ok || err != nil || !reflect.DeepEqual(clock.sleeps, wantSleeps) {
+		t.Errorf("want not ok after sleeps %v, but got %v, %v after %v", wantSleeps, ok, err, clock.sleeps)
+	}
+
+	// A failed request is an error
+	ts.Close()
+	_, err = s.PkgPageOK(context.Background(), "fmt", false)
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// goVersionRegex matches a Go version as accepted by -since,
+// e.g. "1.20", "go1.20" or "1.20.3".
+var goVersionRegex = regexp.MustCompile(`^(go)?1(\.[0-9]+){0,2}$`)
+
+// loadAPIVersions returns the Go version in which each std lib pkg and
+// identifier was added, as per the api files of the GOROOT in use (see
+// buildContext), loading them if necessary. The versions are only used
+// for annotations, so failure to load them is logged but not returned.
+func loadAPIVersions(app *App) *resolve.APIVersions {
+	if app.apiVersions != nil {
+		return app.apiVersions
+	}
+
+	goroot := buildContext(app).GOROOT
+	var err error
+	app.apiVersions, err = resolve.LoadAPIVersions(goroot)
+	if err != nil {
+		log.Printf("failed to load std lib api versions: %v", err)
+		app.apiVersions = &resolve.APIVersions{}
+	}
+	return app.apiVersions
+}
+
+// printAddedIn prints the Go version in which the opened std lib pkg (or
+// its symbol with fragment) was added, and with -since, warns if that's
+// newer than the -since version.
+func printAddedIn(app *App, pkg, fragment string) {
+	versions := loadAPIVersions(app)
+	name := pkg
+	if fragment != "" {
+		name += "#" + fragment
+	}
+
+	v := versions.AddedIn(pkg, fragment)
+	if v != "" {
+		fmt.Printf("%s: added in %s\n", name, v)
+	}
+
+	if msg := sinceWarning(app, versions, pkg, fragment); msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+}
+
+// sinceWarning returns a warning message if std lib pkg, or its symbol
+// with fragment, was added after the -since version. Otherwise, empty
+// string is returned.
+func sinceWarning(app *App, versions *resolve.APIVersions, pkg, fragment string) string {
+	if app.flagSince == "" {
+		return ""
+	}
+
+	// The pkg may itself be newer than -since, and fragment may not
+	// be an identifier, e.g. "example_Println".
+	name, v := pkg, versions.Version(pkg, "")
+	if fv := versions.Version(pkg, fragment); fragment != "" && fv != "" {
+		name, v = pkg+"#"+fragment, fv
+	}
+
+	if v == "" || !resolve.NewerGoVersion(v, app.flagSince) {
+		return ""
+	}
+	return fmt.Sprintf("Warning: %s was added in Go %s, which is newer than -since %s",
+		name, strings.TrimPrefix(v, "go"), strings.TrimPrefix(app.flagSince, "go"))
+}
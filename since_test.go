@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/gohdoc/server"
+)
+
+func TestSinceWarning(t *testing.T) {
+	// testdata/goroot/api holds a few lines of the real api files
+	app := &App{srv: &server.Server{GOROOT: "testdata/goroot"}}
+	versions := loadAPIVersions(app)
+
+	if v := versions.AddedIn("slices", "Clone"); v != "Go 1.21" {
+		t.Errorf("want slices#Clone added in Go 1.21 but got %q", v)
+	}
+	if v := versions.AddedIn("fmt", "Println"); v != "" {
+		t.Errorf("want no annotation for Go 1.0 symbol fmt#Println but got %q", v)
+	}
+
+	testCases := []struct {
+		since    string
+		pkg      string
+		fragment string
+		want     string
+	}{
+		{since: "1.20", pkg: "slices", fragment: "Clone", want: "slices#Clone was added in Go 1.21, which is newer than -since 1.20"},
+		{since: "go1.20", pkg: "slices", want: "slices was added in Go 1.21, which is newer than -since 1.20"},
+		{since: "1.20", pkg: "slices", fragment: "example_Clone", want: "slices was added in Go 1.21"},
+		{since: "1.18.3", pkg: "fmt", fragment: "Appendf", want: "fmt#Appendf was added in Go 1.19"},
+		{since: "1.19", pkg: "fmt", fragment: "Appendf"},
+		{since: "1.21", pkg: "slices", fragment: "Clone"},
+		{since: "1.0", pkg: "fmt", fragment: "Println"},
+		{since: "1.0", pkg: "github.com/my/pkg", fragment: "Func"},
+		{pkg: "slices", fragment: "Clone"},
+	}
+	for _, tc := range testCases {
+		app.flagSince = tc.since
+		got := sinceWarning(app, versions, tc.pkg, tc.fragment)
+		if (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+			t.Errorf("-since %s %s#%s: want %q but got %q", tc.since, tc.pkg, tc.fragment, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+
+	"github.com/neilotoole/gohdoc/resolve"
+)
+
+// cmdSource opens a browser at the declaration of a symbol in the
+// godoc http server's source view, e.g. "gohdoc -source fmt#Println".
+func cmdSource(app *App) error {
+	if len(app.args) < 1 || len(app.args) > 2 {
+		return fmt.Errorf("source command takes one arg (or a pkg and a symbol), e.g. fmt#Println or fmt Println")
+	}
+
+	err := loadPkgList(app)
+	if err != nil {
+		return err
+	}
+
+	res, err := resolvePkg(app)
+	if err != nil {
+		return err
+	}
+	if res.Fragment == "" {
+		return fmt.Errorf("source command requires a symbol, e.g. %s#MyFunc", res.Pkg)
+	}
+
+	bp, err := buildContext(app).Import(res.Pkg, app.cwd, 0)
+	if err != nil {
+		return fmt.Errorf("failed to locate source for pkg %s: %v", res.Pkg, err)
+	}
+
+	filename, line, err := resolve.FindDecl(app.ctx, bp.Dir, resolve.GoFiles(bp), res.Fragment)
+	if err != nil {
+		return fmt.Errorf("pkg %s: %v", res.Pkg, err)
+	}
+
+	return openBrowser(app, app.srv.SrcURL(res.Pkg, filename, line))
+}
+
+// importLocalPkg locates the source of the pkg referred to by the cmd
+// line arg, without consulting the godoc http server. The arg is treated
+// as a dir if such a dir exists, otherwise it is treated as an import path.
+func importLocalPkg(app *App) (bp *build.Package, fragment string, err error) {
+	arg := resolve.ParseArg(app.cwd, app.args)
+	bp, err = resolve.Local(app.ctx, buildContext(app), app.cwd, arg)
+	if err != nil {
+		return nil, "", err
+	}
+	return bp, arg.Fragment, nil
+}
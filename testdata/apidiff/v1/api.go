@@ -0,0 +1,30 @@
+// Package api is v1 of a pkg used to test gohdoc -diff.
+package api
+
+// Version is the version.
+const Version = "v1"
+
+// Removed is removed in v2.
+func Removed() {}
+
+// Renamed has its param renamed in v2.
+func Renamed(a int) {}
+
+// Changed has its signature changed in v2.
+func Changed(a int) error { return nil }
+
+// T is a type.
+type T struct {
+	A int
+	b int
+}
+
+// M has its receiver changed in v2.
+func (t T) M() {}
+
+// I is an interface.
+type I interface {
+	Read() error
+}
+
+func unexported() {}
@@ -0,0 +1,31 @@
+// Package api is v2 of a pkg used to test gohdoc -diff.
+package api
+
+// Version is the version.
+const Version = "v2"
+
+// Renamed has its param renamed in v2.
+func Renamed(b int) {}
+
+// Changed has its signature changed in v2.
+func Changed(a int, s string) error { return nil }
+
+// Added is added in v2.
+func Added() {}
+
+// T is a type.
+type T struct {
+	A int
+	B string
+}
+
+// M has its receiver changed in v2.
+func (t *T) M() {}
+
+// I is an interface.
+type I interface {
+	Read() error
+	Close() error
+}
+
+func unexported(x int) {}
@@ -0,0 +1,31 @@
+// Package example is used by gohdoc tests that load a package from
+// source.
+package example
+
+import "fmt"
+
+// Greeting is the default greeting.
+const Greeting = "hello"
+
+// Verbose enables verbose output.
+var Verbose bool
+
+// Greeter greets people.
+type Greeter struct {
+	// Name is the name of the greeter.
+	Name string
+}
+
+// NewGreeter returns a new Greeter.
+func NewGreeter(name string) *Greeter {
+	return &Greeter{Name: name}
+}
+
+// Greet returns a greeting for who.
+func (g *Greeter) Greet(who string) string {
+	return fmt.Sprintf("%s %s, from %s", Greeting, who, g.Name)
+}
+
+func greet() string {
+	return NewGreeter("example").Greet("world")
+}
@@ -0,0 +1,13 @@
+package example
+
+import "fmt"
+
+func ExampleNewGreeter() {
+	g := NewGreeter("gohdoc")
+	fmt.Println(g.Greet("world"))
+	// Output: hello world, from gohdoc
+}
+
+func ExampleGreeter_Greet_formal() {
+	fmt.Println(NewGreeter("Sir").Greet("Madam"))
+}
@@ -0,0 +1,15 @@
+// Package atomic is a small subset of the std lib's sync/atomic, used by
+// gohdoc tests that annotate std lib symbols with the Go version that
+// they were added in (as per testdata/goroot/api).
+package atomic
+
+// LoadInt64 atomically loads *addr.
+func LoadInt64(addr *int64) int64 { return *addr }
+
+// An Int64 is an atomic int64.
+type Int64 struct {
+	v int64
+}
+
+// Load atomically loads and returns the value stored in x.
+func (x *Int64) Load() int64 { return LoadInt64(&x.v) }
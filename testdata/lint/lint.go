@@ -0,0 +1,74 @@
+package lint
+
+import "strings"
+
+// Valid is documented correctly. It refers to [Documented.Method],
+// [strings.Builder], and [the spec].
+//
+// # Heading
+//
+//   - indented list item
+//
+// [the spec]: https://go.dev/ref/spec
+func Valid() {}
+
+func Undocumented() {}
+
+// This comment does not start with the name.
+func BadPrefix() {}
+
+// A Documented may be preceded by an article.
+type Documented struct {
+	// Field is a field.
+	Field string
+}
+
+// Method refers to [Documented.Field] and [Documented.Missing].
+func (d Documented) Method() string {
+	return strings.ToUpper(d.Field)
+}
+
+// BadFormat has problems.
+//
+// #Heading
+//
+// - unindented list item
+//
+// See [Nowhere].
+//
+// [bad link]: not-a-url
+func BadFormat() {}
+
+// Old does something.
+//
+// Deprecated: it is old.
+func Old() {}
+
+// OldWithAlternative does something.
+//
+// Deprecated: use [Valid] instead.
+func OldWithAlternative() {}
+
+// Constants that share a doc comment.
+const (
+	GroupA = 1
+	GroupB = 2
+)
+
+var (
+	// UngroupedDoc has a doc comment.
+	UngroupedDoc = 1
+	Bare         = 2
+)
+
+// Parsed is checked as godoc parses it, so text in a code block
+// isn't checked:
+//
+//	#notAHeading
+//	- not a list item, nor a link to [Nowhere]
+//
+// A paragraph that runs into a heading:
+// # Heading
+//
+//   - a list item with a link to [Missing]
+func Parsed() {}
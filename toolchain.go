@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+// initToolchain selects the Go toolchain specified by -go or -goroot,
+// if either is set, so that the std lib docs (and pkgs) are those of that
+// toolchain. Each toolchain has its own godoc http server: a running
+// server started with the toolchain's GOROOT is used, or else one will
+// be started on a free port.
+func initToolchain(app *App) error {
+	if app.flagGo == "" && app.flagGOROOT == "" {
+		return nil
+	}
+	if app.flagGo != "" && app.flagGOROOT != "" {
+		return fmt.Errorf("-go and -goroot are mutually exclusive")
+	}
+	if app.srv.Remote() {
+		return fmt.Errorf("-go and -goroot require a local godoc http server, but remote server %s is configured",
+			app.srv.BaseURL())
+	}
+
+	var tc *resolve.Toolchain
+	var err error
+	if app.flagGOROOT != "" {
+		tc, err = resolve.GOROOTToolchain(app.flagGOROOT)
+	} else {
+		tc, err = resolve.FindToolchain(app.ctx, app.flagGo)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("using toolchain %s: %s", tc.Version, tc.GOROOT)
+	app.srv.GOROOT = tc.GOROOT
+
+	// The go commands that gohdoc runs (e.g. to list pkgs), and that a
+	// started server runs, should be those of the toolchain.
+	for k, v := range map[string]string{
+		"GOROOT":      tc.GOROOT,
+		"GOTOOLCHAIN": "local",
+		"PATH":        filepath.Join(tc.GOROOT, "bin") + string(os.PathListSeparator) + os.Getenv("PATH"),
+	} {
+		err = os.Setenv(k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return selectServerPort(app, func(p server.Process) bool {
+		return sameGOROOT(p, tc.GOROOT)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+// viewServerStartTimeout is how long to wait for a godoc http server
+// started in a view module to become accessible. It's longer than
+// the default, because godoc first loads the module's build list.
+const viewServerStartTimeout = time.Second * 30
+
+// cmdOpenVersion opens the godoc of pkg at a module version, e.g. for arg
+// "github.com/foo/bar@v1.4.0#Client". The module version is downloaded
+// into the module cache (as with "go run pkg@version"), and a view module
+// that requires it is created. Because godoc serves the dependencies of
+// the module in its current dir at the required versions, a godoc http
+// server running in the view module's dir is used, or started if needed.
+func cmdOpenVersion(app *App, arg resolve.Arg) error {
+	if app.srv.Remote() {
+		return fmt.Errorf("opening pkg@version requires a local godoc http server, but remote server %s is configured",
+			app.srv.BaseURL())
+	}
+
+	dir, mod, err := resolve.ViewModule(app.ctx, arg.Pkg, arg.Version)
+	if err != nil {
+		return err
+	}
+
+	err = requireViewServer(app, dir)
+	if err != nil {
+		return err
+	}
+
+	ok, err := app.srv.PkgPageOK(app.ctx, arg.Pkg, true)
+	if err != nil {
+		return fmt.Errorf("failed to verify pkg page %s@%s: %v", arg.Pkg, mod.Version, err)
+	}
+	if !ok {
+		return fmt.Errorf("pkg %s not found in module %s@%s", arg.Pkg, mod.Path, mod.Version)
+	}
+
+	if arg.Symbol != "" {
+		arg.Fragment, err = symbolFragment(app, arg.Pkg, arg.Symbol)
+		if err != nil {
+			return err
+		}
+	}
+
+	return openPkgPage(app, arg.Pkg, arg.Fragment)
+}
+
+// requireViewServer configures app.srv to use the godoc http server
+// running in view module dir, starting one on a free port if necessary.
+func requireViewServer(app *App, dir string) error {
+	app.srv.Dir = dir
+	app.srv.StartTimeout = viewServerStartTimeout
+
+	err := selectServerPort(app, func(p server.Process) bool {
+		return p.Dir == dir && sameGOROOT(p, app.srv.GOROOT)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = app.srv.Require(app.ctx)
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"net/http"
+	"strings"
+
+	"github.com/neilotoole/gohdoc/server"
+	"github.com/neilotoole/gohdoc/watch"
+)
+
+// cmdWatch opens the pkg arg's godoc in the browser, via a gohdoc proxy
+// in front of the godoc http server. The pkg's .go files are watched,
+// and when they change, the proxy tells the browser to reload the page.
+// The godoc http server parses the pkg source for each pkg page request,
+// so a reload is sufficient to pick up edits: there's no need to restart
+// the server. cmdWatch runs until interrupted.
+func cmdWatch(app *App) error {
+	if len(app.args) > 1 {
+		return fmt.Errorf("watch command takes maximum one arg, but received %d: [%s]",
+			len(app.args), strings.Join(app.args, " "))
+	}
+
+	err := loadPkgList(app)
+	if err != nil {
+		return err
+	}
+
+	res, err := resolvePkg(app)
+	if err != nil {
+		return err
+	}
+
+	bp, err := buildContext(app).Import(res.Pkg, app.cwd, build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("failed to locate source for pkg %s: %v", res.Pkg, err)
+	}
+
+	rl := watch.NewReloader()
+	handler, err := watch.NewProxy(app.srv, rl)
+	if err != nil {
+		return err
+	}
+	proxyURL, stop, err := server.ServeLocal(handler)
+	if err != nil {
+		return fmt.Errorf("failed to start watch proxy: %v", err)
+	}
+	defer stop()
+
+	// The proxy serves the server's pages relative to its root.
+	pagePath := strings.TrimPrefix(app.srv.PkgURL(res.Pkg, res.Fragment), app.srv.BaseURL())
+	err = openBrowser(app, strings.TrimSuffix(proxyURL.String(), "/")+pagePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Watching %s for changes; press Ctrl-C to exit\n", bp.Dir)
+	watch.Dir(app.ctx, bp.Dir, rl.Reload)
+	return nil
+}
+
+// serveAndOpen serves handler on a random loopback port, and opens
+// a browser at the server's root. It serves until interrupted.
+func serveAndOpen(app *App, handler http.Handler) error {
+	baseURL, stop, err := server.ServeLocal(handler)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	err = openBrowser(app, baseURL.String())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Serving; press Ctrl-C to exit")
+	<-app.ctx.Done()
+	return nil
+}
@@ -0,0 +1,219 @@
+// Package watch serves a godoc http server's pages via a proxy that
+// reloads them in the browser when the pkg source changes.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neilotoole/gohdoc/server"
+)
+
+const (
+	// reloadPath is the proxy path on which the browser listens
+	// for reload events.
+	reloadPath = "/_gohdoc/reload"
+
+	// pollInterval is how often the pkg dir is checked for changes.
+	pollInterval = time.Millisecond * 500
+
+	// reloadScript is injected into each HTML page served by the
+	// proxy. It reloads the page when gohdoc sends an event.
+	reloadScript = `<script>
+(function() {
+  var es = new EventSource("` + reloadPath + `");
+  es.onmessage = function() { window.location.reload(); };
+})();
+</script>
+`
+)
+
+// NewProxy returns a handler that proxies requests to the godoc http
+// server srv, injecting a script into HTML pages that reloads the page
+// when rl sends a reload event. The proxy serves the server's pages
+// relative to its root.
+func NewProxy(srv *server.Server, rl *Reloader) (http.Handler, error) {
+	target, err := url.Parse(srv.BaseURL())
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	if client, ok := srv.Client.(*http.Client); ok && client.Transport != nil {
+		// e.g. a client that trusts a remote server's custom CA
+		proxy.Transport = client.Transport
+	}
+
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+		srv.Auth.Authorize(r)
+		// We need to be able to modify the body, so ask for it uncompressed.
+		r.Header.Del("Accept-Encoding")
+	}
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		body = injectReloadScript(body)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(reloadPath, rl)
+	mux.Handle("/", proxy)
+	return mux, nil
+}
+
+// injectReloadScript returns the HTML page with reloadScript
+// inserted before the closing body tag (or appended, if there's no
+// such tag).
+func injectReloadScript(page []byte) []byte {
+	i := bytes.LastIndex(page, []byte("</body>"))
+	if i < 0 {
+		return append(page, reloadScript...)
+	}
+
+	out := make([]byte, 0, len(page)+len(reloadScript))
+	out = append(out, page[:i]...)
+	out = append(out, reloadScript...)
+	out = append(out, page[i:]...)
+	return out
+}
+
+// Reloader is a http.Handler that streams reload events (as
+// server-sent events) to connected browsers.
+type Reloader struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+// NewReloader returns a Reloader with no connected browsers.
+func NewReloader() *Reloader {
+	return &Reloader{clients: map[chan struct{}]struct{}{}}
+}
+
+func (rl *Reloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+	rl.mu.Lock()
+	rl.clients[ch] = struct{}{}
+	rl.mu.Unlock()
+
+	defer func() {
+		rl.mu.Lock()
+		delete(rl.clients, ch)
+		rl.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// Reload sends a reload event to each connected browser.
+func (rl *Reloader) Reload() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	log.Printf("sending reload event to %d browser(s)", len(rl.clients))
+	for ch := range rl.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// A reload is already pending for this client.
+		}
+	}
+}
+
+// Dir polls dir for changes to .go files, invoking onChange
+// for each change, until ctx is done.
+func Dir(ctx context.Context, dir string, onChange func()) {
+	prev := goFileModTimes(dir)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := goFileModTimes(dir)
+		if !modTimesEqual(prev, cur) {
+			log.Printf("detected change in %s", dir)
+			onChange()
+		}
+		prev = cur
+	}
+}
+
+// goFileModTimes returns the mod time of each .go file in dir.
+func goFileModTimes(dir string) map[string]time.Time {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("failed to read dir %s: %v", dir, err)
+		return nil
+	}
+
+	m := map[string]time.Time{}
+	for _, fi := range fis {
+		if !fi.IsDir() && filepath.Ext(fi.Name()) == ".go" {
+			m[fi.Name()] = fi.ModTime()
+		}
+	}
+	return m
+}
+
+// modTimesEqual returns true if a and b have the same files and mod times.
+func modTimesEqual(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, t := range a {
+		if bt, ok := b[name]; !ok || !bt.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
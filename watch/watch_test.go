@@ -0,0 +1,99 @@
+package watch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/gohdoc/server"
+)
+
+func TestInjectReloadScript(t *testing.T) {
+	testCases := []struct {
+		page string
+		want string
+	}{
+		{page: "<html><body>doc</body></html>", want: "<html><body>doc" + reloadScript + "</body></html>"},
+		{page: "<p>doc</p>", want: "<p>doc</p>" + reloadScript},
+		{page: "", want: reloadScript},
+	}
+
+	for _, tc := range testCases {
+		got := string(injectReloadScript([]byte(tc.page)))
+		if got != tc.want {
+			t.Errorf("want %q but got %q", tc.want, got)
+		}
+	}
+}
+
+func TestModTimesEqual(t *testing.T) {
+	now := time.Now()
+	a := map[string]time.Time{"a.go": now, "b.go": now}
+
+	if !modTimesEqual(a, map[string]time.Time{"a.go": now, "b.go": now}) {
+		t.Error("expected equal")
+	}
+	if modTimesEqual(a, map[string]time.Time{"a.go": now, "b.go": now.Add(time.Second)}) {
+		t.Error("expected not equal: changed mod time")
+	}
+	if modTimesEqual(a, map[string]time.Time{"a.go": now, "c.go": now}) {
+		t.Error("expected not equal: renamed file")
+	}
+	if modTimesEqual(a, map[string]time.Time{"a.go": now}) {
+		t.Error("expected not equal: removed file")
+	}
+}
+
+func TestProxy(t *testing.T) {
+	godoc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".css") {
+			w.Header().Set("Content-Type", "text/css")
+			_, _ = w.Write([]byte("body {}"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><body>" + r.URL.Path + "</body></html>"))
+	}))
+	defer godoc.Close()
+
+	u, err := url.Parse(godoc.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := NewProxy(&server.Server{Port: port}, NewReloader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	get := func(pth string) string {
+		resp, err := http.Get(proxy.URL + pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if got, want := get("/pkg/fmt/"), "<html><body>/pkg/fmt/"+reloadScript+"</body></html>"; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+	if got, want := get("/lib/godoc/style.css"), "body {}"; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/neilotoole/gohdoc/resolve"
+	"github.com/neilotoole/gohdoc/scrape"
+	"github.com/neilotoole/gohdoc/server"
+)
+
+// requireServer requires the godoc http server (see server.Server.Require).
+// A local server that pre-existed may have been started in another module
+// or GOPATH, in which case it doesn't serve the current workspace. If so,
+// the mismatch is explained, and a server that does serve the workspace is
+// used instead: with -restart, the mismatched server is replaced on its
+// port; otherwise, a matching server on another port is used, or started.
+func requireServer(app *App) error {
+	pkgPage, err := app.srv.Require(app.ctx)
+	if err != nil {
+		return err
+	}
+	if app.srv.Remote() || app.srv.Proc != nil || app.findWorkspace == nil {
+		// A server that we started serves the workspace
+		return nil
+	}
+
+	ws, err := app.findWorkspace(app.ctx, app.cwd)
+	if err != nil {
+		// Not fatal: use the server anyway
+		log.Printf("failed to determine workspace of %s: %v", app.cwd, err)
+		return nil
+	}
+	if servesWorkspace(pkgPage, ws) {
+		return nil
+	}
+
+	mismatch := describeMismatch(app, ws)
+	if app.flagRestart {
+		return restartServer(app, mismatch)
+	}
+
+	port := app.srv.Port
+	err = selectServerPort(app, func(p server.Process) bool {
+		return ws.Contains(p.Dir) && sameGOROOT(p, app.srv.GOROOT)
+	})
+	if err != nil {
+		return err
+	}
+	app.srv.Reset()
+	pkgPage, err = app.srv.Require(app.ctx)
+	if err != nil {
+		return err
+	}
+
+	if app.srv.Proc != nil {
+		fmt.Fprintf(os.Stderr, "%s.\nStarted a godoc http server on port %d instead; use -restart to replace the server on port %d.\n",
+			mismatch, app.srv.Port, port)
+		return nil
+	}
+	if !servesWorkspace(pkgPage, ws) {
+		return fmt.Errorf("%s, nor does the server on port %d; use -restart to replace the server on port %d",
+			mismatch, app.srv.Port, port)
+	}
+	log.Printf("%s; using the godoc http server on port %d instead", mismatch, app.srv.Port)
+	return nil
+}
+
+// servesWorkspace returns true if ws is served by the godoc http
+// server with /pkg/ page pkgPage.
+func servesWorkspace(pkgPage []byte, ws *resolve.Workspace) bool {
+	pkgs, err := scrape.Packages(bytes.NewReader(pkgPage))
+	if err != nil {
+		// Not fatal: assume the server serves ws
+		log.Printf("failed to parse godoc http server pkg list: %v", err)
+		return true
+	}
+	return ws.Served(pkgs)
+}
+
+// describeMismatch returns a message explaining that the godoc http
+// server on app.srv.Port doesn't serve workspace ws, including where
+// the server was started, if known.
+func describeMismatch(app *App, ws *resolve.Workspace) string {
+	what := "GOPATH pkg " + ws.ImportPath
+	if ws.Module {
+		what = "module " + ws.ImportPath
+	}
+	msg := fmt.Sprintf("The godoc http server on port %d doesn't serve %s (in %s)", app.srv.Port, what, ws.Root)
+
+	if p := serverProcess(app); p != nil && p.Dir != "" {
+		msg += fmt.Sprintf(": it was started [%d] in %s", p.PID, p.Dir)
+	}
+	return msg
+}
+
+// serverProcess returns the godoc http server process listening on
+// app.srv.Port, or nil if not found.
+func serverProcess(app *App) *server.Process {
+	ps, err := listProcesses(app)
+	if err != nil {
+		log.Printf("failed to list godoc http servers: %v", err)
+		return nil
+	}
+	for _, p := range ps {
+		if p.Port() == app.srv.Port {
+			return &p
+		}
+	}
+	return nil
+}
+
+// restartServer kills the godoc http server on app.srv.Port, which
+// doesn't serve the current workspace as per mismatch, and starts a
+// server in its place.
+func restartServer(app *App, mismatch string) error {
+	p := serverProcess(app)
+	if p == nil {
+		return fmt.Errorf("%s, and its process was not found, so it can't be restarted", mismatch)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s.\nRestarting it in %s.\n", mismatch, app.cwd)
+	err := killProcess(app, *p)
+	if err != nil {
+		return fmt.Errorf("failed to kill godoc http server [%d]: %v", p.PID, err)
+	}
+
+	// Wait for the killed server to release its port
+	if !app.srv.WaitPortFree() {
+		log.Printf("port %d is still in use after killing godoc http server [%d]", app.srv.Port, p.PID)
+	}
+
+	app.srv.Reset()
+	_, err = app.srv.Require(app.ctx)
+	return err
+}
+
+// listProcesses returns the running godoc http server processes,
+// via app.listProcesses if set.
+func listProcesses(app *App) ([]server.Process, error) {
+	if app.listProcesses != nil {
+		return app.listProcesses(app.ctx)
+	}
+	return server.ListProcesses(app.ctx)
+}
+
+// killProcess kills the godoc http server process p, via
+// app.killProcess if set.
+func killProcess(app *App, p server.Process) error {
+	if app.killProcess != nil {
+		return app.killProcess(app.ctx, p)
+	}
+	return p.Kill(app.ctx)
+}